@@ -1,65 +1,329 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/mattn/algia/internal/domain"
+	"os"
+	"strings"
+	"sync/atomic"
 
 	"github.com/urfave/cli/v2"
 
+	"github.com/fatih/color"
 	"github.com/nbd-wtf/go-nostr"
 	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/nbd-wtf/go-nostr/sdk"
 )
 
 func DoBMList(cCtx *cli.Context) error {
 	n := cCtx.Int("n")
 	j := cCtx.Bool("json")
 	extra := cCtx.Bool("extra")
+	raw := cCtx.Bool("raw")
 
 	cfg := cCtx.App.Metadata["config"].(*domain.Config)
 
+	signer, err := cfg.Signer()
+	if err != nil {
+		return err
+	}
+	npub, err := signer.GetPublicKey(context.TODO())
+	if err != nil {
+		return err
+	}
+
+	public, private, _, err := loadBookmarks(cfg, npub)
+	if err != nil {
+		return err
+	}
+
+	if raw {
+		type entry struct {
+			Tag     nostr.Tag `json:"tag"`
+			Private bool      `json:"private"`
+		}
+		var entries []entry
+		for _, tag := range public {
+			if isBookmarkTag(tag) {
+				entries = append(entries, entry{Tag: tag})
+			}
+		}
+		for _, tag := range private {
+			if isBookmarkTag(tag) {
+				entries = append(entries, entry{Tag: tag, Private: true})
+			}
+		}
+		for _, e := range entries {
+			if j {
+				json.NewEncoder(os.Stdout).Encode(e)
+				continue
+			}
+			color.Set(color.FgHiBlue)
+			fmt.Print(bookmarkString(e.Tag))
+			color.Set(color.Reset)
+			if e.Private {
+				fmt.Print(" (private)")
+			}
+			fmt.Println()
+		}
+		return nil
+	}
+
 	// get followers
 	followsMap, err := cfg.GetFollows(cCtx.String("a"))
 	if err != nil {
 		return err
 	}
 
-	var sk string
-	var npub string
-	if _, s, err := nip19.Decode(cfg.PrivateKey); err == nil {
-		sk = s.(string)
-	} else {
+	be := []string{}
+	for _, tag := range append(public, private...) {
+		if isBookmarkTag(tag) && tag[0] == "e" {
+			be = append(be, tag[1])
+		}
+	}
+	if n > 0 && len(be) > n {
+		be = be[len(be)-n:]
+	}
+	if len(be) == 0 {
+		return nil
+	}
+	filter := nostr.Filter{
+		Kinds: []int{nostr.KindTextNote},
+		IDs:   be,
+	}
+	eevs := cfg.Events(filter)
+	cfg.PrintEvents(eevs, followsMap, nil, j, extra)
+	return nil
+}
+
+func DoBMPost(cCtx *cli.Context) error {
+	if cCtx.Args().Len() == 0 {
+		return cli.ShowSubcommandHelp(cCtx)
+	}
+	private := cCtx.Bool("private")
+	force := cCtx.Bool("force")
+
+	cfg := cCtx.App.Metadata["config"].(*domain.Config)
+
+	signer, err := cfg.Signer()
+	if err != nil {
 		return err
 	}
-	if npub, err = nostr.GetPublicKey(sk); err != nil {
+	npub, err := signer.GetPublicKey(context.TODO())
+	if err != nil {
 		return err
 	}
 
-	// get timeline
-	filter := nostr.Filter{
-		Kinds:   []int{nostr.KindCategorizedBookmarksList},
+	public, privs, found, err := loadBookmarks(cfg, npub)
+	if err != nil {
+		return err
+	}
+	if !found && !force {
+		return errors.New("cannot find bookmark list (use --force to create a new one)")
+	}
+	for _, arg := range cCtx.Args().Slice() {
+		tag, err := bookmarkTag(arg)
+		if err != nil {
+			return err
+		}
+		if hasBookmark(public, tag) || hasBookmark(privs, tag) {
+			continue
+		}
+		if private {
+			privs = append(privs, tag)
+		} else {
+			public = append(public, tag)
+		}
+	}
+	return publishBookmarks(cfg, signer, npub, public, privs)
+}
+
+func DoBMDelete(cCtx *cli.Context) error {
+	if cCtx.Args().Len() == 0 {
+		return cli.ShowSubcommandHelp(cCtx)
+	}
+	force := cCtx.Bool("force")
+
+	cfg := cCtx.App.Metadata["config"].(*domain.Config)
+
+	signer, err := cfg.Signer()
+	if err != nil {
+		return err
+	}
+	npub, err := signer.GetPublicKey(context.TODO())
+	if err != nil {
+		return err
+	}
+
+	public, private, found, err := loadBookmarks(cfg, npub)
+	if err != nil {
+		return err
+	}
+	if !found && !force {
+		return errors.New("cannot find bookmark list (use --force to create a new one)")
+	}
+	for _, arg := range cCtx.Args().Slice() {
+		tag, err := bookmarkTag(arg)
+		if err != nil {
+			return err
+		}
+		if !hasBookmark(public, tag) && !hasBookmark(private, tag) {
+			return fmt.Errorf("'%s' is not bookmarked", arg)
+		}
+		public = removeBookmark(public, tag)
+		private = removeBookmark(private, tag)
+	}
+	return publishBookmarks(cfg, signer, npub, public, private)
+}
+
+// loadBookmarks returns the public and private tags of the bookmark list, and
+// whether the list is found. Tags other than entries such as title are kept to
+// publish them again. The legacy kind 30001 list is used when there is no kind
+// 10003 list yet.
+func loadBookmarks(cfg *domain.Config, npub string) (nostr.Tags, nostr.Tags, bool, error) {
+	public := nostr.Tags{}
+	private := nostr.Tags{}
+
+	evs := cfg.Events(nostr.Filter{
+		Kinds:   []int{domain.KindBookmarkList},
 		Authors: []string{npub},
-		Tags:    nostr.TagMap{"d": []string{"bookmark"}},
-		Limit:   n,
+		Limit:   1,
+	})
+	if len(evs) > 0 {
+		list := evs[len(evs)-1]
+		public = append(public, list.Tags...)
+		tags, err := cfg.PrivateTags(list)
+		if err != nil {
+			return nil, nil, false, fmt.Errorf("cannot decrypt private bookmarks: %w", err)
+		}
+		private = append(private, tags...)
+		return public, private, true, nil
 	}
 
-	be := []string{}
-	evs := cfg.Events(filter)
+	// legacy bookmarks (content is already decrypted)
+	evs = cfg.Events(nostr.Filter{
+		Kinds:   []int{nostr.KindCategorizedBookmarksList},
+		Authors: []string{npub},
+		Tags:    nostr.TagMap{"d": []string{"bookmark"}},
+	})
 	for _, ev := range evs {
 		for _, tag := range ev.Tags {
-			if len(tag) > 1 && tag[0] == "e" {
-				be = append(be, tag[1:]...)
+			if isBookmarkTag(tag) && !hasBookmark(public, tag) {
+				public = append(public, tag)
+			}
+		}
+		var tags nostr.Tags
+		if err := json.Unmarshal([]byte(ev.Content), &tags); err == nil {
+			for _, tag := range tags {
+				if isBookmarkTag(tag) && !hasBookmark(private, tag) {
+					private = append(private, tag)
+				}
 			}
 		}
 	}
-	filter = nostr.Filter{
-		Kinds: []int{nostr.KindTextNote},
-		IDs:   be,
+	return public, private, len(evs) > 0, nil
+}
+
+func publishBookmarks(cfg *domain.Config, signer domain.Signer, npub string, public, private nostr.Tags) error {
+	ev := nostr.Event{}
+	ev.PubKey = npub
+	ev.Tags = public
+	content, err := cfg.EncryptTags(private)
+	if err != nil {
+		return err
+	}
+	ev.Content = content
+	ev.CreatedAt = nostr.Now()
+	ev.Kind = domain.KindBookmarkList
+	if err := signer.SignEvent(context.TODO(), &ev); err != nil {
+		return err
+	}
+
+	var success atomic.Int64
+	cfg.Do(domain.Relay{Write: true}, func(ctx context.Context, relay *nostr.Relay) bool {
+		err := relay.Publish(ctx, ev)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, relay.URL, err)
+		} else {
+			success.Add(1)
+		}
+		return true
+	})
+	if success.Load() == 0 {
+		return errors.New("cannot post bookmarks")
 	}
-	eevs := cfg.Events(filter)
-	cfg.PrintEvents(eevs, followsMap, j, extra)
 	return nil
 }
 
-func DoBMPost(cCtx *cli.Context) error {
-	return errors.New("Not Implemented")
+func isBookmarkTag(tag nostr.Tag) bool {
+	if len(tag) < 2 {
+		return false
+	}
+	switch tag[0] {
+	case "e", "a", "t", "r":
+		return true
+	}
+	return false
+}
+
+func hasBookmark(tags nostr.Tags, tag nostr.Tag) bool {
+	for _, t := range tags {
+		if len(t) >= 2 && t[0] == tag[0] && t[1] == tag[1] {
+			return true
+		}
+	}
+	return false
+}
+
+func removeBookmark(tags nostr.Tags, tag nostr.Tag) nostr.Tags {
+	result := nostr.Tags{}
+	for _, t := range tags {
+		if len(t) >= 2 && t[0] == tag[0] && t[1] == tag[1] {
+			continue
+		}
+		result = append(result, t)
+	}
+	return result
+}
+
+// bookmarkTag converts note/nevent/naddr, #hashtag or URL into a bookmark entry.
+func bookmarkTag(input string) (nostr.Tag, error) {
+	input = strings.TrimPrefix(input, "nostr:")
+	if strings.HasPrefix(input, "#") && len(input) > 1 {
+		return nostr.Tag{"t", input[1:]}, nil
+	}
+	if strings.HasPrefix(input, "https://") || strings.HasPrefix(input, "http://") {
+		return nostr.Tag{"r", input}, nil
+	}
+	if evp := sdk.InputToEventPointer(input); evp != nil {
+		tag := nostr.Tag{"e", evp.ID}
+		if len(evp.Relays) > 0 {
+			tag = append(tag, evp.Relays[0])
+		}
+		return tag, nil
+	}
+	if prefix, data, err := nip19.Decode(input); err == nil && prefix == "naddr" {
+		ep := data.(nostr.EntityPointer)
+		tag := nostr.Tag{"a", fmt.Sprintf("%d:%s:%s", ep.Kind, ep.PublicKey, ep.Identifier)}
+		if len(ep.Relays) > 0 {
+			tag = append(tag, ep.Relays[0])
+		}
+		return tag, nil
+	}
+	return nil, fmt.Errorf("failed to parse bookmark from '%s'", input)
+}
+
+func bookmarkString(tag nostr.Tag) string {
+	switch tag[0] {
+	case "e":
+		if note, err := nip19.EncodeNote(tag[1]); err == nil {
+			return note
+		}
+	case "t":
+		return "#" + tag[1]
+	}
+	return tag[1]
 }
@@ -6,7 +6,7 @@ import (
 	"fmt"
 	"github.com/mattn/algia/internal/domain"
 	"github.com/nbd-wtf/go-nostr"
-	"github.com/nbd-wtf/nostr-sdk"
+	"github.com/nbd-wtf/go-nostr/sdk"
 	"github.com/urfave/cli/v2"
 	"os"
 	"sync"
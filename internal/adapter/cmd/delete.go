@@ -7,7 +7,7 @@ import (
 	"github.com/mattn/algia/internal/domain"
 	"github.com/nbd-wtf/go-nostr"
 	"github.com/nbd-wtf/go-nostr/nip19"
-	"github.com/nbd-wtf/nostr-sdk"
+	"github.com/nbd-wtf/go-nostr/sdk"
 	"github.com/urfave/cli/v2"
 	"os"
 	"sync/atomic"
@@ -19,13 +19,11 @@ func DoDelete(cCtx *cli.Context) error {
 	cfg := cCtx.App.Metadata["config"].(*domain.Config)
 
 	ev := nostr.Event{}
-	var sk string
-	if _, s, err := nip19.Decode(cfg.PrivateKey); err == nil {
-		sk = s.(string)
-	} else {
+	signer, err := cfg.Signer()
+	if err != nil {
 		return err
 	}
-	if pub, err := nostr.GetPublicKey(sk); err == nil {
+	if pub, err := signer.GetPublicKey(context.TODO()); err == nil {
 		if _, err := nip19.EncodePublicKey(pub); err != nil {
 			return err
 		}
@@ -42,7 +40,7 @@ func DoDelete(cCtx *cli.Context) error {
 	ev.Tags = ev.Tags.AppendUnique(nostr.Tag{"e", id})
 	ev.CreatedAt = nostr.Now()
 	ev.Kind = nostr.KindDeletion
-	if err := ev.Sign(sk); err != nil {
+	if err := signer.SignEvent(context.TODO(), &ev); err != nil {
 		return err
 	}
 
@@ -15,13 +15,13 @@ import (
 
 	"github.com/fatih/color"
 	"github.com/nbd-wtf/go-nostr"
-	"github.com/nbd-wtf/go-nostr/nip04"
 	"github.com/nbd-wtf/go-nostr/nip19"
-	"github.com/nbd-wtf/nostr-sdk"
+	"github.com/nbd-wtf/go-nostr/sdk"
 )
 
 func DoDMList(cCtx *cli.Context) error {
 	j := cCtx.Bool("json")
+	legacy := cCtx.Bool("legacy")
 
 	cfg := cCtx.App.Metadata["config"].(*domain.Config)
 
@@ -31,46 +31,61 @@ func DoDMList(cCtx *cli.Context) error {
 		return err
 	}
 
-	var sk string
-	var npub string
-	if _, s, err := nip19.Decode(cfg.PrivateKey); err == nil {
-		sk = s.(string)
-	} else {
-		return err
-	}
-	if npub, err = nostr.GetPublicKey(sk); err != nil {
+	npub, err := cfg.PublicKey()
+	if err != nil {
 		return err
 	}
 
 	// get timeline
-	filter := nostr.Filter{
-		Kinds:   []int{nostr.KindEncryptedDirectMessage},
-		Authors: []string{npub},
+	var peers []string
+	if legacy {
+		filter := nostr.Filter{
+			Kinds:   []int{nostr.KindEncryptedDirectMessage},
+			Authors: []string{npub},
+		}
+		for _, ev := range cfg.Events(filter) {
+			if tag := ev.Tags.GetFirst([]string{"p"}); tag != nil {
+				peers = append(peers, tag.Value())
+			}
+		}
+	} else {
+		filter := nostr.Filter{
+			Kinds: []int{domain.KindGiftWrap},
+			Tags:  nostr.TagMap{"p": []string{npub}},
+		}
+		for _, ev := range cfg.EventsFrom(cfg.DMRelays(npub), filter) {
+			if ev.Kind != domain.KindPrivateDirectMessage {
+				continue
+			}
+			for p := range dmParticipants(ev) {
+				if p != npub {
+					peers = append(peers, p)
+				}
+			}
+		}
 	}
 
-	evs := cfg.Events(filter)
 	type entry struct {
-		name   string
-		pubkey string
+		Name   string `json:"name"`
+		Pubkey string `json:"pubkey"`
 	}
 	users := []entry{}
 	m := map[string]struct{}{}
-	for _, ev := range evs {
-		p := ev.Tags.GetFirst([]string{"p"}).Value()
+	for _, p := range peers {
 		if _, ok := m[p]; ok {
 			continue
 		}
+		m[p] = struct{}{}
 		if profile, ok := followsMap[p]; ok {
-			m[p] = struct{}{}
 			p, _ = nip19.EncodePublicKey(p)
 			users = append(users, entry{
-				name:   profile.DisplayName,
-				pubkey: p,
+				Name:   profile.DisplayName,
+				Pubkey: p,
 			})
 		} else {
 			users = append(users, entry{
-				name:   p,
-				pubkey: p,
+				Name:   p,
+				Pubkey: p,
 			})
 		}
 	}
@@ -83,11 +98,11 @@ func DoDMList(cCtx *cli.Context) error {
 
 	for _, user := range users {
 		color.Set(color.FgHiRed)
-		fmt.Print(user.name)
+		fmt.Print(user.Name)
 		color.Set(color.Reset)
 		fmt.Print(": ")
 		color.Set(color.FgHiBlue)
-		fmt.Println(user.pubkey)
+		fmt.Println(user.Pubkey)
 		color.Set(color.Reset)
 	}
 	return nil
@@ -97,18 +112,12 @@ func DoDMTimeline(cCtx *cli.Context) error {
 	u := cCtx.String("u")
 	j := cCtx.Bool("json")
 	extra := cCtx.Bool("extra")
+	legacy := cCtx.Bool("legacy")
 
 	cfg := cCtx.App.Metadata["config"].(*domain.Config)
 
-	var sk string
-	var npub string
-	var err error
-	if _, s, err := nip19.Decode(cfg.PrivateKey); err == nil {
-		sk = s.(string)
-	} else {
-		return err
-	}
-	if npub, err = nostr.GetPublicKey(sk); err != nil {
+	npub, err := cfg.PublicKey()
+	if err != nil {
 		return err
 	}
 
@@ -128,36 +137,80 @@ func DoDMTimeline(cCtx *cli.Context) error {
 	}
 
 	// get timeline
-	filter := nostr.Filter{
-		Kinds:   []int{nostr.KindEncryptedDirectMessage},
-		Authors: []string{npub, pub},
-		Tags:    nostr.TagMap{"p": []string{npub, pub}},
-		Limit:   9999,
+	if legacy {
+		filter := nostr.Filter{
+			Kinds:   []int{nostr.KindEncryptedDirectMessage},
+			Authors: []string{npub, pub},
+			Tags:    nostr.TagMap{"p": []string{npub, pub}},
+			Limit:   9999,
+		}
+
+		evs := cfg.Events(filter)
+		cfg.PrintEvents(evs, followsMap, nil, j, extra)
+		return nil
 	}
 
-	evs := cfg.Events(filter)
-	cfg.PrintEvents(evs, followsMap, j, extra)
+	filter := nostr.Filter{
+		Kinds: []int{domain.KindGiftWrap},
+		Tags:  nostr.TagMap{"p": []string{npub}},
+		Limit: 9999,
+	}
+	var evs []*nostr.Event
+	for _, ev := range cfg.EventsFrom(cfg.DMRelays(npub), filter) {
+		if isConversation(ev, npub, pub) {
+			evs = append(evs, ev)
+		}
+	}
+	cfg.PrintEvents(evs, followsMap, nil, j, extra)
 	return nil
 }
 
+func dmParticipants(ev *nostr.Event) map[string]struct{} {
+	ps := map[string]struct{}{ev.PubKey: {}}
+	for _, tag := range ev.Tags {
+		if len(tag) >= 2 && tag[0] == "p" {
+			ps[tag[1]] = struct{}{}
+		}
+	}
+	return ps
+}
+
+// isConversation reports whether the decoded private message is between npub and pub only.
+func isConversation(ev *nostr.Event, npub, pub string) bool {
+	if ev.Kind != domain.KindPrivateDirectMessage {
+		return false
+	}
+	ps := dmParticipants(ev)
+	if _, ok := ps[pub]; !ok {
+		return false
+	}
+	if _, ok := ps[npub]; !ok {
+		return false
+	}
+	return len(ps) == 2 || (len(ps) == 1 && pub == npub)
+}
+
 func DoDMPost(cCtx *cli.Context) error {
 	u := cCtx.String("u")
 	stdin := cCtx.Bool("stdin")
-	if !stdin && cCtx.Args().Len() == 0 {
+	edit := cCtx.Bool("edit")
+	if !stdin && !edit && cCtx.Args().Len() == 0 {
 		return cli.ShowSubcommandHelp(cCtx)
 	}
+	if stdin && edit {
+		return errors.New("--edit can not be used with --stdin")
+	}
 	sensitive := cCtx.String("sensitive")
+	legacy := cCtx.Bool("legacy")
 
 	cfg := cCtx.App.Metadata["config"].(*domain.Config)
 
-	var sk string
-	if _, s, err := nip19.Decode(cfg.PrivateKey); err == nil {
-		sk = s.(string)
-	} else {
+	signer, err := cfg.Signer()
+	if err != nil {
 		return err
 	}
 	ev := nostr.Event{}
-	if npub, err := nostr.GetPublicKey(sk); err == nil {
+	if npub, err := signer.GetPublicKey(context.TODO()); err == nil {
 		if _, err := nip19.EncodePublicKey(npub); err != nil {
 			return err
 		}
@@ -175,6 +228,17 @@ func DoDMPost(cCtx *cli.Context) error {
 	} else {
 		ev.Content = strings.Join(cCtx.Args().Slice(), "\n")
 	}
+	if edit {
+		content, err := editContent(ev.Content, "")
+		if err != nil {
+			return err
+		}
+		if content == "" {
+			fmt.Fprintln(os.Stderr, "aborted: empty message")
+			return nil
+		}
+		ev.Content = content
+	}
 	if strings.TrimSpace(ev.Content) == "" {
 		return errors.New("content is empty")
 	}
@@ -195,17 +259,32 @@ func DoDMPost(cCtx *cli.Context) error {
 
 	ev.Tags = ev.Tags.AppendUnique(nostr.Tag{"p", pub})
 	ev.CreatedAt = nostr.Now()
+	if edit {
+		if ok, err := confirmEvent(&ev); err != nil {
+			return err
+		} else if !ok {
+			fmt.Fprintln(os.Stderr, "aborted")
+			return nil
+		}
+	}
+	if !legacy {
+		return postPrivateMessage(cfg, ev, pub, cfg.DMRelays(pub), cfg.DMRelays(ev.PubKey))
+	}
+	return postLegacyMessage(cfg, ev, pub)
+}
+
+func postLegacyMessage(cfg *domain.Config, ev nostr.Event, pub string) error {
 	ev.Kind = nostr.KindEncryptedDirectMessage
 
-	ss, err := nip04.ComputeSharedSecret(ev.PubKey, sk)
+	signer, err := cfg.Signer()
 	if err != nil {
 		return err
 	}
-	ev.Content, err = nip04.Encrypt(ev.Content, ss)
+	ev.Content, err = signer.Nip04Encrypt(context.TODO(), pub, ev.Content)
 	if err != nil {
 		return err
 	}
-	if err := ev.Sign(sk); err != nil {
+	if err := signer.SignEvent(context.TODO(), &ev); err != nil {
 		return err
 	}
 
@@ -224,3 +303,49 @@ func DoDMPost(cCtx *cli.Context) error {
 	}
 	return nil
 }
+
+func postPrivateMessage(cfg *domain.Config, rumor nostr.Event, pub string, urls, ownURLs []string) error {
+	rumor.Kind = domain.KindPrivateDirectMessage
+
+	publish := func(urls []string, ev *nostr.Event) int64 {
+		var success atomic.Int64
+		f := func(ctx context.Context, relay *nostr.Relay) bool {
+			err := relay.Publish(ctx, *ev)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, relay.URL, err)
+			} else {
+				success.Add(1)
+			}
+			return true
+		}
+		if len(urls) == 0 {
+			cfg.Do(domain.Relay{Write: true}, f)
+		} else {
+			cfg.DoURLs(urls, f)
+		}
+		return success.Load()
+	}
+
+	wrap, err := cfg.GiftWrap(rumor, pub)
+	if err != nil {
+		return err
+	}
+	if len(urls) == 0 && cfg.Verbose {
+		fmt.Println("no DM relays found, using write relays")
+	}
+	if publish(urls, wrap) == 0 {
+		return errors.New("cannot post")
+	}
+
+	// keep a copy for ourselves to read the conversation later
+	if pub != rumor.PubKey {
+		wrap, err = cfg.GiftWrap(rumor, rumor.PubKey)
+		if err != nil {
+			return err
+		}
+		if publish(ownURLs, wrap) == 0 {
+			fmt.Fprintln(os.Stderr, "cannot store a copy of the message")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"github.com/mattn/algia/internal/domain"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/sdk"
+)
+
+func DoDMChat(cCtx *cli.Context) error {
+	u := cCtx.String("u")
+	n := cCtx.Int("n")
+	legacy := cCtx.Bool("legacy")
+
+	cfg := cCtx.App.Metadata["config"].(*domain.Config)
+
+	npub, err := cfg.PublicKey()
+	if err != nil {
+		return err
+	}
+
+	if u == "me" {
+		u = npub
+	}
+	var pub string
+	if pp := sdk.InputToProfile(context.TODO(), u); pp != nil {
+		pub = pp.PublicKey
+	} else {
+		return fmt.Errorf("failed to parse pubkey from '%s'", u)
+	}
+	// get followers
+	followsMap, err := cfg.GetFollows(cCtx.String("a"))
+	if err != nil {
+		return err
+	}
+
+	var urls, ownURLs []string
+	for k, v := range cfg.Relays {
+		if v.Read {
+			urls = append(urls, k)
+		}
+	}
+	if !legacy {
+		ownURLs = cfg.DMRelays(npub)
+		urls = append(urls, ownURLs...)
+	}
+	peerURLs := ownURLs
+	if pub != npub && !legacy {
+		peerURLs = cfg.DMRelays(pub)
+	}
+
+	// get history
+	start := nostr.Now()
+	var history []*nostr.Event
+	var filter nostr.Filter
+	if legacy {
+		filter = nostr.Filter{
+			Kinds:   []int{nostr.KindEncryptedDirectMessage},
+			Authors: []string{npub, pub},
+			Tags:    nostr.TagMap{"p": []string{npub, pub}},
+		}
+		f := filter
+		f.Limit = n
+		history = cfg.Events(f)
+	} else {
+		filter = nostr.Filter{
+			Kinds: []int{domain.KindGiftWrap},
+			Tags:  nostr.TagMap{"p": []string{npub}},
+		}
+		f := filter
+		f.Limit = 9999
+		for _, ev := range cfg.EventsFrom(ownURLs, f) {
+			if isConversation(ev, npub, pub) {
+				history = append(history, ev)
+			}
+		}
+	}
+	if len(history) > n {
+		history = history[len(history)-n:]
+	}
+
+	seen := map[string]struct{}{}
+	for _, ev := range history {
+		seen[ev.ID] = struct{}{}
+		cfg.PrintEvent(ev, followsMap, "")
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	// gift wraps have randomized timestamps up to two days in the past
+	since := start
+	if !legacy {
+		since -= nostr.Timestamp((2 * 24 * time.Hour).Seconds())
+	}
+	filter.Since = &since
+	pool := nostr.NewSimplePool(ctx)
+	go func() {
+		for ie := range pool.SubMany(ctx, urls, nostr.Filters{filter}) {
+			ev := ie.Event
+			if err := cfg.Decode(ev); err != nil {
+				continue
+			}
+			if !legacy && (!isConversation(ev, npub, pub) || ev.CreatedAt < start) {
+				continue
+			}
+			if _, ok := seen[ev.ID]; !ok {
+				seen[ev.ID] = struct{}{}
+				cfg.PrintEvent(ev, followsMap, "")
+			}
+		}
+	}()
+
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case line, ok := <-lines:
+			if !ok {
+				return nil
+			}
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			ev := nostr.Event{
+				PubKey:    npub,
+				CreatedAt: nostr.Now(),
+				Tags:      nostr.Tags{nostr.Tag{"p", pub}},
+				Content:   line,
+			}
+			if legacy {
+				err = postLegacyMessage(cfg, ev, pub)
+			} else {
+				err = postPrivateMessage(cfg, ev, pub, peerURLs, ownURLs)
+			}
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}
+	}
+}
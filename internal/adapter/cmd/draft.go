@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/mattn/algia/internal/domain"
+	"os"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/sdk"
+)
+
+// draftUserTag keeps the users given with -u in drafts, which are mentioned on
+// publish. p tags are not used since drafts of other clients have p tags for
+// the mentions in the content.
+const draftUserTag = "algia-user"
+
+// syncDrafts returns whether drafts are synced with relays (NIP-37).
+func syncDrafts(cCtx *cli.Context, cfg *domain.Config) bool {
+	return cfg.DraftSync || cCtx.Bool("sync")
+}
+
+func DoDraftSave(cCtx *cli.Context) error {
+	id := cCtx.String("id")
+	if id == "" {
+		id = domain.NewDraftID()
+	}
+
+	cfg := cCtx.App.Metadata["config"].(*domain.Config)
+
+	ev, err := composeNote(cCtx, cfg)
+	if err != nil || ev == nil {
+		return err
+	}
+	for _, u := range cCtx.StringSlice("u") {
+		pp := sdk.InputToProfile(context.TODO(), u)
+		if pp == nil {
+			return fmt.Errorf("failed to parse pubkey from '%s'", u)
+		}
+		ev.Tags = ev.Tags.AppendUnique(nostr.Tag{draftUserTag, pp.PublicKey})
+	}
+	if err := cfg.SaveDraft(id, ev); err != nil {
+		return err
+	}
+	if syncDrafts(cCtx, cfg) {
+		if err := cfg.PushDraft(id, ev); err != nil {
+			return err
+		}
+	}
+	fmt.Println(id)
+	return nil
+}
+
+func DoDraftList(cCtx *cli.Context) error {
+	j := cCtx.Bool("json")
+
+	cfg := cCtx.App.Metadata["config"].(*domain.Config)
+
+	if syncDrafts(cCtx, cfg) {
+		if err := cfg.PullDrafts(); err != nil {
+			return err
+		}
+	}
+	drafts, err := cfg.Drafts()
+	if err != nil {
+		return err
+	}
+	if j {
+		enc := json.NewEncoder(os.Stdout)
+		for _, draft := range drafts {
+			enc.Encode(draft)
+		}
+		return nil
+	}
+	for _, draft := range drafts {
+		fmt.Printf("%s %s kind:%d %s\n",
+			draft.ID,
+			draft.Event.CreatedAt.Time().Format(time.DateTime),
+			draft.Event.Kind,
+			summary(draft.Event.Content))
+	}
+	return nil
+}
+
+// loadDraft returns the draft of the first argument, pulling drafts if sync.
+func loadDraft(cCtx *cli.Context, cfg *domain.Config) (string, *nostr.Event, error) {
+	id := cCtx.Args().First()
+	if id == "" {
+		return "", nil, cli.ShowSubcommandHelp(cCtx)
+	}
+	if syncDrafts(cCtx, cfg) {
+		if err := cfg.PullDrafts(); err != nil {
+			return "", nil, err
+		}
+	}
+	ev, err := cfg.LoadDraft(id)
+	return id, ev, err
+}
+
+func DoDraftEdit(cCtx *cli.Context) error {
+	cfg := cCtx.App.Metadata["config"].(*domain.Config)
+
+	id, ev, err := loadDraft(cCtx, cfg)
+	if err != nil || ev == nil {
+		return err
+	}
+	content, err := editContent(ev.Content, "")
+	if err != nil {
+		return err
+	}
+	if content == "" {
+		fmt.Fprintln(os.Stderr, "aborted: empty note")
+		return nil
+	}
+	ev.Content = content
+	ev.CreatedAt = nostr.Now()
+	if err := cfg.SaveDraft(id, ev); err != nil {
+		return err
+	}
+	if syncDrafts(cCtx, cfg) {
+		return cfg.PushDraft(id, ev)
+	}
+	return nil
+}
+
+// takeDraftUsers removes the users given on save from the tags and returns
+// them.
+func takeDraftUsers(ev *nostr.Event) []string {
+	var users []string
+	tags := nostr.Tags{}
+	for _, tag := range ev.Tags {
+		if len(tag) >= 2 && tag[0] == draftUserTag {
+			users = append(users, tag[1])
+		} else {
+			tags = append(tags, tag)
+		}
+	}
+	ev.Tags = tags
+	return users
+}
+
+func DoDraftPublish(cCtx *cli.Context) error {
+	var at nostr.Timestamp
+	if s := cCtx.String("at"); s != "" {
+		var err error
+		if at, err = parseScheduleTime(s); err != nil {
+			return err
+		}
+	}
+
+	cfg := cCtx.App.Metadata["config"].(*domain.Config)
+
+	id, ev, err := loadDraft(cCtx, cfg)
+	if err != nil || ev == nil {
+		return err
+	}
+	if ev.PubKey, err = cfg.PublicKey(); err != nil {
+		return err
+	}
+	if err := completeNote(cCtx, cfg, ev, takeDraftUsers(ev)); err != nil {
+		return err
+	}
+	if at != 0 {
+		err = scheduleNote(cfg, ev, at)
+	} else {
+		err = publishNote(cfg, ev)
+	}
+	if err != nil {
+		return err
+	}
+	if err := cfg.RemoveDraft(id); err != nil {
+		return err
+	}
+	if syncDrafts(cCtx, cfg) {
+		return cfg.PushDraft(id, nil)
+	}
+	return nil
+}
+
+func DoDraftRemove(cCtx *cli.Context) error {
+	if cCtx.Args().Len() == 0 {
+		return cli.ShowSubcommandHelp(cCtx)
+	}
+
+	cfg := cCtx.App.Metadata["config"].(*domain.Config)
+
+	for _, id := range cCtx.Args().Slice() {
+		// the draft may be only on the relays
+		if !syncDrafts(cCtx, cfg) {
+			if _, err := cfg.LoadDraft(id); err != nil {
+				return err
+			}
+		}
+		if err := cfg.RemoveDraft(id); err != nil {
+			return err
+		}
+		if syncDrafts(cCtx, cfg) {
+			if err := cfg.PushDraft(id, nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+func TestTakeDraftUsers(t *testing.T) {
+	ev := &nostr.Event{
+		Tags: nostr.Tags{
+			{"p", "mentioned"},
+			{draftUserTag, "user1"},
+			{"t", "algia"},
+			{draftUserTag, "user2"},
+		},
+	}
+	users := takeDraftUsers(ev)
+	if want := []string{"user1", "user2"}; !reflect.DeepEqual(users, want) {
+		t.Fatalf("want users %v, but got %v", want, users)
+	}
+	// p tags of the mentions in the content are kept as they are
+	if want := (nostr.Tags{{"p", "mentioned"}, {"t", "algia"}}); !reflect.DeepEqual(ev.Tags, want) {
+		t.Fatalf("want tags %v, but got %v", want, ev.Tags)
+	}
+}
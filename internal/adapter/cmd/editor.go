@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+)
+
+const scissors = "------------------------ >8 ------------------------"
+
+// editContent opens $EDITOR with the text and the comment below the scissors
+// line, and returns the text above it. Empty string is returned if the text
+// is left empty.
+func editContent(text, comment string) (string, error) {
+	f, err := os.CreateTemp("", "algia-*.md")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+
+	var buf strings.Builder
+	buf.WriteString(text)
+	if !strings.HasSuffix(text, "\n") {
+		buf.WriteString("\n")
+	}
+	buf.WriteString("\n" + scissors + "\n")
+	buf.WriteString("Do not modify or remove the line above.\nEverything below it will be ignored.\n")
+	if comment != "" {
+		buf.WriteString("\n" + comment + "\n")
+	}
+	_, err = f.WriteString(buf.String())
+	f.Close()
+	if err != nil {
+		return "", err
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+	args := strings.Fields(editor)
+	cmd := exec.Command(args[0], append(args[1:], f.Name())...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("failed to run editor: %w", err)
+	}
+
+	b, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", err
+	}
+	content, _, _ := strings.Cut(strings.ReplaceAll(string(b), "\r\n", "\n"), scissors)
+	return strings.TrimSpace(content), nil
+}
+
+// quoteComment returns the note quoted for the template of the reply.
+func quoteComment(ev *nostr.Event) string {
+	var buf strings.Builder
+	if note, err := nip19.EncodeNote(ev.ID); err == nil {
+		buf.WriteString(note + "\n")
+	}
+	for _, line := range strings.Split(ev.Content, "\n") {
+		buf.WriteString("> " + line + "\n")
+	}
+	return buf.String()
+}
+
+// splitFrontMatter returns the values in the front matter and the body.
+func splitFrontMatter(content string) (map[string]string, string) {
+	values := map[string]string{}
+	if !strings.HasPrefix(content, "---\n") {
+		return values, content
+	}
+	head, body, ok := strings.Cut(content[4:], "\n---")
+	if !ok {
+		return values, content
+	}
+	for _, line := range strings.Split(head, "\n") {
+		if k, v, ok := strings.Cut(line, ":"); ok {
+			values[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return values, strings.TrimSpace(body)
+}
+
+// confirmEvent shows the content and the tags of the event, and asks whether
+// to publish it.
+func confirmEvent(ev *nostr.Event) (bool, error) {
+	fmt.Fprintf(os.Stderr, "kind: %d\n", ev.Kind)
+	for _, tag := range ev.Tags {
+		fmt.Fprintf(os.Stderr, "tag: %s\n", strings.Join(tag, " "))
+	}
+	fmt.Fprintln(os.Stderr, strings.Repeat("-", 40))
+	fmt.Fprintln(os.Stderr, ev.Content)
+	fmt.Fprintln(os.Stderr, strings.Repeat("-", 40))
+	fmt.Fprint(os.Stderr, "publish? [y/N] ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return false, err
+	}
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
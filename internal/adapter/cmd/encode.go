@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mattn/algia/internal/domain"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/btcsuite/btcd/btcutil/bech32"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+)
+
+// eachInput calls f with each argument, or each line of stdin if no arguments.
+func eachInput(cCtx *cli.Context, f func(string) error) error {
+	failed := 0
+	do := func(input string) {
+		input = strings.TrimSpace(input)
+		if input == "" {
+			return
+		}
+		if err := f(input); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", input, err)
+			failed++
+		}
+	}
+	if cCtx.Args().Len() > 0 {
+		for _, arg := range cCtx.Args().Slice() {
+			do(arg)
+		}
+	} else {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			do(scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to convert %d input(s)", failed)
+	}
+	return nil
+}
+
+func DoDecode(cCtx *cli.Context) error {
+	enc := json.NewEncoder(os.Stdout)
+	return eachInput(cCtx, func(input string) error {
+		prefix, value, err := domain.DecodeEntity(input)
+		if err != nil {
+			return err
+		}
+		return enc.Encode(struct {
+			Type string `json:"type"`
+			Data any    `json:"data"`
+		}{prefix, value})
+	})
+}
+
+// hexID returns the event id of hex, note or nevent.
+func hexID(input string) (string, error) {
+	if nostr.IsValid32ByteHex(input) {
+		return input, nil
+	}
+	prefix, value, err := domain.DecodeEntity(input)
+	if err != nil {
+		return "", err
+	}
+	switch prefix {
+	case "note":
+		return value.(string), nil
+	case "nevent":
+		return value.(nostr.EventPointer).ID, nil
+	}
+	return "", fmt.Errorf("'%s' is not an event id", input)
+}
+
+// hexPubKey returns the public key of hex, npub or nprofile.
+func hexPubKey(input string) (string, error) {
+	if nostr.IsValidPublicKey(input) {
+		return input, nil
+	}
+	prefix, value, err := domain.DecodeEntity(input)
+	if err != nil {
+		return "", err
+	}
+	switch prefix {
+	case "npub":
+		return value.(string), nil
+	case "nprofile":
+		return value.(nostr.ProfilePointer).PublicKey, nil
+	}
+	return "", fmt.Errorf("'%s' is not a public key", input)
+}
+
+// encodeEvent is nip19.EncodeEvent with kind.
+func encodeEvent(id string, relays []string, author string, kind int) (string, error) {
+	if kind == 0 {
+		return nip19.EncodeEvent(id, relays, author)
+	}
+	buf := &bytes.Buffer{}
+	b, err := hex.DecodeString(id)
+	if err != nil || len(b) != 32 {
+		return "", fmt.Errorf("invalid id '%s'", id)
+	}
+	writeTLV(buf, nip19.TLVDefault, b)
+	for _, url := range relays {
+		writeTLV(buf, nip19.TLVRelay, []byte(url))
+	}
+	if pubkey, _ := hex.DecodeString(author); len(pubkey) == 32 {
+		writeTLV(buf, nip19.TLVAuthor, pubkey)
+	}
+	kb := make([]byte, 4)
+	binary.BigEndian.PutUint32(kb, uint32(kind))
+	writeTLV(buf, nip19.TLVKind, kb)
+
+	bits5, err := bech32.ConvertBits(buf.Bytes(), 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	return bech32.Encode("nevent", bits5)
+}
+
+func writeTLV(buf *bytes.Buffer, typ uint8, value []byte) {
+	buf.WriteByte(typ)
+	buf.WriteByte(uint8(len(value)))
+	buf.Write(value)
+}
+
+func doEncode(cCtx *cli.Context, f func(string) (string, error)) error {
+	uri := cCtx.Bool("uri")
+	return eachInput(cCtx, func(input string) error {
+		s, err := f(input)
+		if err != nil {
+			return err
+		}
+		if uri {
+			s = "nostr:" + s
+		}
+		fmt.Println(s)
+		return nil
+	})
+}
+
+func DoEncodeNpub(cCtx *cli.Context) error {
+	return doEncode(cCtx, func(input string) (string, error) {
+		pub, err := hexPubKey(input)
+		if err != nil {
+			return "", err
+		}
+		return nip19.EncodePublicKey(pub)
+	})
+}
+
+func DoEncodeNsec(cCtx *cli.Context) error {
+	return doEncode(cCtx, func(input string) (string, error) {
+		if !nostr.IsValid32ByteHex(input) {
+			return "", errors.New("not a hex private key")
+		}
+		return nip19.EncodePrivateKey(input)
+	})
+}
+
+func DoEncodeNote(cCtx *cli.Context) error {
+	return doEncode(cCtx, func(input string) (string, error) {
+		id, err := hexID(input)
+		if err != nil {
+			return "", err
+		}
+		return nip19.EncodeNote(id)
+	})
+}
+
+func DoEncodeNprofile(cCtx *cli.Context) error {
+	relays := cCtx.StringSlice("relay")
+	return doEncode(cCtx, func(input string) (string, error) {
+		pub, err := hexPubKey(input)
+		if err != nil {
+			return "", err
+		}
+		return nip19.EncodeProfile(pub, relays)
+	})
+}
+
+func DoEncodeNevent(cCtx *cli.Context) error {
+	relays := cCtx.StringSlice("relay")
+	kind := cCtx.Int("kind")
+	var author string
+	if a := cCtx.String("author"); a != "" {
+		var err error
+		if author, err = hexPubKey(a); err != nil {
+			return err
+		}
+	}
+	return doEncode(cCtx, func(input string) (string, error) {
+		id, err := hexID(input)
+		if err != nil {
+			return "", err
+		}
+		return encodeEvent(id, relays, author, kind)
+	})
+}
+
+func DoEncodeNaddr(cCtx *cli.Context) error {
+	relays := cCtx.StringSlice("relay")
+	return doEncode(cCtx, func(input string) (string, error) {
+		// kind:pubkey:identifier as in "a" tag
+		parts := strings.SplitN(input, ":", 3)
+		if len(parts) != 3 {
+			return "", errors.New("must be kind:pubkey:identifier")
+		}
+		kind, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return "", fmt.Errorf("invalid kind '%s'", parts[0])
+		}
+		pub, err := hexPubKey(parts[1])
+		if err != nil {
+			return "", err
+		}
+		return nip19.EncodeEntity(pub, kind, parts[2], relays)
+	})
+}
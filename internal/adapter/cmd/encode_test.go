@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+)
+
+func TestEncodeEvent(t *testing.T) {
+	id := "b9f5441e45ca39179320e0031cfb18e34078673dcc3d3e3a3b3a981760aa5696"
+	author := "aad8555ba7327bd587d6577cf7c3b45647b67e5f4f7b5dd145d9fa029afb234a"
+	tests := []nostr.EventPointer{
+		{ID: id},
+		{ID: id, Kind: nostr.KindTextNote},
+		{ID: id, Relays: []string{"wss://relay.example.com", "wss://nos.lol"}, Author: author, Kind: nostr.KindArticle},
+		{ID: id, Author: author, Kind: 1 << 20},
+		{ID: id, Relays: []string{"wss://relay.example.com"}, Author: author},
+	}
+	for _, want := range tests {
+		s, err := encodeEvent(want.ID, want.Relays, want.Author, want.Kind)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prefix, v, err := nip19.Decode(s)
+		if err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+		if prefix != "nevent" {
+			t.Fatalf("want nevent, but got %s", prefix)
+		}
+		got := v.(nostr.EventPointer)
+		if len(got.Relays) == 0 {
+			got.Relays = nil
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("want %+v, but got %+v", want, got)
+		}
+	}
+
+	if _, err := encodeEvent("xyz", nil, "", nostr.KindTextNote); err == nil {
+		t.Fatal("want error for invalid id")
+	}
+}
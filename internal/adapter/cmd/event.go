@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mattn/algia/internal/domain"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+func DoEvent(cCtx *cli.Context) error {
+	stdin := cCtx.Bool("stdin")
+	signOnly := cCtx.Bool("sign-only")
+	if signOnly && !cCtx.IsSet("kind") && !cCtx.IsSet("content") && !cCtx.IsSet("tag") {
+		stdin = true
+	}
+
+	cfg := cCtx.App.Metadata["config"].(*domain.Config)
+
+	signer, err := cfg.Signer()
+	if err != nil {
+		return err
+	}
+
+	ev := nostr.Event{}
+	if stdin {
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(b, &ev); err != nil {
+			return fmt.Errorf("invalid event JSON: %w", err)
+		}
+		if ev.Tags == nil {
+			ev.Tags = nostr.Tags{}
+		}
+		if ev.CreatedAt == 0 {
+			ev.CreatedAt = nostr.Now()
+		}
+	} else {
+		ev.Kind = cCtx.Int("kind")
+		ev.Content = cCtx.String("content")
+		ev.Tags = nostr.Tags{}
+		for _, s := range cCtx.StringSlice("tag") {
+			tag, err := parseTag(s)
+			if err != nil {
+				return err
+			}
+			ev.Tags = append(ev.Tags, tag)
+		}
+		ev.CreatedAt = nostr.Now()
+	}
+	if ev.PubKey, err = signer.GetPublicKey(context.TODO()); err != nil {
+		return err
+	}
+	if err := signer.SignEvent(context.TODO(), &ev); err != nil {
+		return err
+	}
+
+	if signOnly {
+		return json.NewEncoder(os.Stdout).Encode(ev)
+	}
+
+	var success atomic.Int64
+	cfg.Do(domain.Relay{Write: true}, func(ctx context.Context, relay *nostr.Relay) bool {
+		err := relay.Publish(ctx, ev)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, relay.URL, err)
+		} else {
+			fmt.Println(relay.URL, "OK")
+			success.Add(1)
+		}
+		return true
+	})
+	if success.Load() == 0 {
+		return errors.New("cannot post")
+	}
+	return nil
+}
+
+// parseTag parses key=value or key=value1;value2 into a tag.
+func parseTag(s string) (nostr.Tag, error) {
+	k, v, ok := strings.Cut(s, "=")
+	if k == "" {
+		return nil, fmt.Errorf("invalid tag '%s'", s)
+	}
+	if !ok {
+		return nostr.Tag{k}, nil
+	}
+	return append(nostr.Tag{k}, strings.Split(v, ";")...), nil
+}
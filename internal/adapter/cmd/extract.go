@@ -7,7 +7,7 @@ import (
 
 const (
 	urlPattern     = `https?://[-A-Za-z0-9+&@#\/%?=~_|!:,.;\(\)]+`
-	mentionPattern = `@[a-zA-Z0-9.]+`
+	mentionPattern = `@[a-zA-Z0-9._-]+(?:@[a-zA-Z0-9.-]+)?`
 	emojiPattern   = `:[a-zA-Z0-9]+:`
 	tagPattern     = `\B#\S+`
 )
@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mattn/algia/internal/domain"
+	"os"
+	"sync/atomic"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/fatih/color"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/nbd-wtf/go-nostr/sdk"
+)
+
+func DoFollowing(cCtx *cli.Context) error {
+	j := cCtx.Bool("json")
+
+	cfg := cCtx.App.Metadata["config"].(*domain.Config)
+
+	npub, err := cfg.PublicKey()
+	if err != nil {
+		return err
+	}
+
+	ev, _ := cfg.ContactList(npub)
+	if ev == nil {
+		return errors.New("cannot find contact list")
+	}
+	if !cfg.Offline {
+		if err := cfg.UpdateFollows(cCtx.String("a"), ev); err != nil {
+			return err
+		}
+	}
+
+	type entry struct {
+		Pubkey  string `json:"pubkey"`
+		Relay   string `json:"relay,omitempty"`
+		Petname string `json:"petname,omitempty"`
+		Name    string `json:"name,omitempty"`
+	}
+	for _, tag := range ev.Tags.GetAll([]string{"p", ""}) {
+		e := entry{Pubkey: tag[1]}
+		if len(tag) > 2 {
+			e.Relay = tag[2]
+		}
+		if len(tag) > 3 {
+			e.Petname = tag[3]
+		}
+		if p, ok := cfg.Follows[tag[1]]; ok {
+			e.Name = p.Name
+		}
+		if j {
+			json.NewEncoder(os.Stdout).Encode(e)
+			continue
+		}
+		if npub, err := nip19.EncodePublicKey(e.Pubkey); err == nil {
+			fmt.Print(npub)
+		} else {
+			fmt.Print(e.Pubkey)
+		}
+		if e.Petname != "" || e.Name != "" {
+			name := e.Name
+			if e.Petname != "" {
+				name = e.Petname
+			}
+			color.Set(color.FgHiRed)
+			fmt.Print(" " + name)
+			color.Set(color.Reset)
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+func DoFollow(cCtx *cli.Context) error {
+	if cCtx.Args().Len() == 0 {
+		return cli.ShowSubcommandHelp(cCtx)
+	}
+	petname := cCtx.String("petname")
+	if petname != "" && cCtx.Args().Len() > 1 {
+		return errors.New("--petname can be used with only one user")
+	}
+
+	return updateContactList(cCtx, func(tags nostr.Tags) (nostr.Tags, error) {
+		for _, arg := range cCtx.Args().Slice() {
+			pp := sdk.InputToProfile(context.TODO(), arg)
+			if pp == nil {
+				return nil, fmt.Errorf("failed to parse pubkey from '%s'", arg)
+			}
+			relay := ""
+			if len(pp.Relays) > 0 {
+				relay = pp.Relays[0]
+			}
+
+			found := false
+			for i, t := range tags {
+				if len(t) < 2 || t[0] != "p" || t[1] != pp.PublicKey {
+					continue
+				}
+				found = true
+				// keep the existing relay hint and petname unless given
+				r, name := relay, petname
+				if r == "" && len(t) > 2 {
+					r = t[2]
+				}
+				if name == "" && len(t) > 3 {
+					name = t[3]
+				}
+				tags[i] = contactTag(pp.PublicKey, r, name)
+			}
+			if !found {
+				tags = append(tags, contactTag(pp.PublicKey, relay, petname))
+			}
+		}
+		return tags, nil
+	})
+}
+
+func DoUnfollow(cCtx *cli.Context) error {
+	if cCtx.Args().Len() == 0 {
+		return cli.ShowSubcommandHelp(cCtx)
+	}
+
+	return updateContactList(cCtx, func(tags nostr.Tags) (nostr.Tags, error) {
+		for _, arg := range cCtx.Args().Slice() {
+			pp := sdk.InputToProfile(context.TODO(), arg)
+			if pp == nil {
+				return nil, fmt.Errorf("failed to parse pubkey from '%s'", arg)
+			}
+			result := nostr.Tags{}
+			for _, t := range tags {
+				if len(t) >= 2 && t[0] == "p" && t[1] == pp.PublicKey {
+					continue
+				}
+				result = append(result, t)
+			}
+			if len(result) == len(tags) {
+				return nil, fmt.Errorf("'%s' is not followed", arg)
+			}
+			tags = result
+		}
+		return tags, nil
+	})
+}
+
+func contactTag(pub, relay, petname string) nostr.Tag {
+	tag := nostr.Tag{"p", pub}
+	if relay != "" || petname != "" {
+		tag = append(tag, relay)
+	}
+	if petname != "" {
+		tag = append(tag, petname)
+	}
+	return tag
+}
+
+// updateContactList fetches the newest contact list, edits its tags with f and publishes it.
+func updateContactList(cCtx *cli.Context, f func(nostr.Tags) (nostr.Tags, error)) error {
+	force := cCtx.Bool("force")
+
+	cfg := cCtx.App.Metadata["config"].(*domain.Config)
+	if cfg.Offline {
+		return errors.New("cannot update contact list in offline mode")
+	}
+
+	signer, err := cfg.Signer()
+	if err != nil {
+		return err
+	}
+	npub, err := signer.GetPublicKey(context.TODO())
+	if err != nil {
+		return err
+	}
+
+	old, most := cfg.ContactList(npub)
+	if len(cfg.Follows) > most {
+		most = len(cfg.Follows)
+	}
+	ev := nostr.Event{}
+	if old != nil {
+		ev.Tags = append(ev.Tags, old.Tags...)
+		ev.Content = old.Content
+	} else {
+		ev.Tags = nostr.Tags{}
+	}
+	// refuse to overwrite the list with a copy that lost follows
+	if !force {
+		count := len(ev.Tags.GetAll([]string{"p", ""}))
+		if old == nil && most > 0 {
+			return errors.New("cannot find contact list (use --force to create a new one)")
+		}
+		if count < most/2 {
+			return fmt.Errorf("contact list looks truncated: %d follows but %d seen before (use --force to publish anyway)", count, most)
+		}
+	}
+
+	if ev.Tags, err = f(ev.Tags); err != nil {
+		return err
+	}
+	ev.PubKey = npub
+	ev.CreatedAt = nostr.Now()
+	ev.Kind = nostr.KindFollowList
+	if err := signer.SignEvent(context.TODO(), &ev); err != nil {
+		return err
+	}
+
+	var success atomic.Int64
+	cfg.Do(domain.Relay{Write: true}, func(ctx context.Context, relay *nostr.Relay) bool {
+		err := relay.Publish(ctx, ev)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, relay.URL, err)
+		} else {
+			success.Add(1)
+		}
+		return true
+	})
+	if success.Load() == 0 {
+		return errors.New("cannot post contact list")
+	}
+	return cfg.UpdateFollows(cCtx.String("a"), &ev)
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/mattn/algia/internal/domain"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip06"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/nbd-wtf/go-nostr/nip49"
+)
+
+var defaultRelays = map[string]domain.Relay{
+	"wss://relay.nostr.band": {Read: true, Write: true, Search: true},
+	"wss://nos.lol":          {Read: true, Write: true},
+	"wss://relay.damus.io":   {Read: true, Write: true},
+}
+
+func DoInit(cCtx *cli.Context) error {
+	profile := cCtx.Args().First()
+	if profile == "" {
+		profile = cCtx.String("a")
+	}
+	input := cCtx.String("import")
+
+	fp, err := domain.ConfigFile(profile)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(fp); err == nil && !cCtx.Bool("force") {
+		return fmt.Errorf("%s already exists (use --force to overwrite)", fp)
+	}
+
+	var sk string
+	ksb := nip49.NotKnownToHaveBeenHandledInsecurely
+	if input != "" {
+		if input == "-" {
+			line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+			if err != nil && line == "" {
+				return err
+			}
+			input = line
+		}
+		if sk, err = parseSecretKey(strings.TrimSpace(input)); err != nil {
+			return err
+		}
+		ksb = nip49.ClientDoesNotTrackThisData
+	} else {
+		sk = nostr.GeneratePrivateKey()
+	}
+	nsec, err := nip19.EncodePrivateKey(sk)
+	if err != nil {
+		return err
+	}
+
+	cfg := &domain.Config{
+		Relays:       map[string]domain.Relay{},
+		PrivateKey:   nsec,
+		Verbose:      cCtx.Bool("V"),
+		PassphraseFD: cCtx.Int("passphrase-fd"),
+	}
+	if relays := cCtx.StringSlice("relay"); len(relays) > 0 {
+		for _, relay := range relays {
+			cfg.Relays[relay] = domain.Relay{Read: true, Write: true}
+		}
+	} else {
+		for k, v := range defaultRelays {
+			cfg.Relays[k] = v
+		}
+	}
+	if cCtx.Bool("encrypt") {
+		if err := cfg.EncryptKey(ksb); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fp), 0700); err != nil {
+		return err
+	}
+	if err := cfg.Save(profile); err != nil {
+		return err
+	}
+
+	pub, err := cfg.PublicKey()
+	if err != nil {
+		return err
+	}
+	npub, err := nip19.EncodePublicKey(pub)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "wrote %s\n", fp)
+	fmt.Println(npub)
+
+	if !cCtx.Bool("publish") {
+		return nil
+	}
+	// the key is new, so there is no profile to merge
+	if fields := profileFlags(cCtx); len(fields) > 0 {
+		if err := setProfile(cfg, fields, true); err != nil {
+			return err
+		}
+	}
+	return publishRelayList(cfg)
+}
+
+// parseSecretKey accepts nsec, hex or NIP-06 mnemonic words.
+func parseSecretKey(input string) (string, error) {
+	if strings.HasPrefix(input, "nsec") {
+		_, s, err := nip19.Decode(input)
+		if err != nil {
+			return "", err
+		}
+		return s.(string), nil
+	}
+	if b, err := hex.DecodeString(input); err == nil {
+		if len(b) != 32 {
+			return "", errors.New("hex private key must be 32 bytes")
+		}
+		return input, nil
+	}
+	if strings.Contains(input, " ") {
+		if !nip06.ValidateWords(input) {
+			return "", errors.New("invalid mnemonic words")
+		}
+		return nip06.PrivateKeyFromSeed(nip06.SeedFromWords(input))
+	}
+	return "", errors.New("cannot parse private key: use nsec, hex or mnemonic words")
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/mattn/algia/internal/domain"
+	"github.com/mattn/algia/internal/testrelay"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/urfave/cli/v2"
+)
+
+func TestInitPublish(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("XDG_CACHE_HOME", dir)
+
+	r := testrelay.New()
+	defer r.Close()
+
+	app := &cli.App{
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "a"},
+		},
+		Commands: []*cli.Command{
+			{
+				Name: "init",
+				Flags: []cli.Flag{
+					&cli.StringSliceFlag{Name: "relay"},
+					&cli.BoolFlag{Name: "force"},
+					&cli.BoolFlag{Name: "publish"},
+					&cli.StringFlag{Name: "name"},
+					&cli.StringFlag{Name: "about"},
+				},
+				Action: DoInit,
+			},
+		},
+	}
+	err := app.Run([]string{"algia", "init", "--relay", r.URL, "--publish", "--name", "foo", "--about", "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := domain.LoadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cfg.Close()
+	pub, err := cfg.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	evs := r.Events(nostr.Filter{Kinds: []int{nostr.KindProfileMetadata}, Authors: []string{pub}})
+	if len(evs) != 1 {
+		t.Fatalf("want 1 profile, but got %d", len(evs))
+	}
+	var profile domain.Profile
+	if err := json.Unmarshal([]byte(evs[0].Content), &profile); err != nil {
+		t.Fatal(err)
+	}
+	if profile.Name != "foo" || profile.About != "hello" {
+		t.Fatalf("want name %q and about %q, but got %q and %q", "foo", "hello", profile.Name, profile.About)
+	}
+
+	evs = r.Events(nostr.Filter{Kinds: []int{nostr.KindRelayListMetadata}, Authors: []string{pub}})
+	if len(evs) != 1 {
+		t.Fatalf("want 1 relay list, but got %d", len(evs))
+	}
+	if tag := evs[0].Tags.GetFirst([]string{"r", r.URL}); tag == nil {
+		t.Fatalf("want %s in relay list, but got %v", r.URL, evs[0].Tags)
+	}
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mattn/algia/internal/domain"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/nbd-wtf/go-nostr/nip49"
+)
+
+func DoKeyEncrypt(cCtx *cli.Context) error {
+	cfg := cCtx.App.Metadata["config"].(*domain.Config)
+	if cfg.TempRelay {
+		return errors.New("cannot modify privatekey with --relays")
+	}
+	if cfg.PrivateKey == "" {
+		return errors.New("privatekey is not configured")
+	}
+	// the key was stored in plain text until now
+	if err := cfg.EncryptKey(nip49.KnownToHaveBeenHandledInsecurely); err != nil {
+		return err
+	}
+	if err := cfg.Save(cCtx.String("a")); err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, "privatekey is encrypted")
+	return nil
+}
+
+func DoKeyDecrypt(cCtx *cli.Context) error {
+	cfg := cCtx.App.Metadata["config"].(*domain.Config)
+	if cfg.TempRelay {
+		return errors.New("cannot modify privatekey with --relays")
+	}
+	if !strings.HasPrefix(cfg.PrivateKey, "ncryptsec") {
+		return errors.New("privatekey is not encrypted")
+	}
+	sk, err := cfg.DecryptKey()
+	if err != nil {
+		return err
+	}
+	nsec, err := nip19.EncodePrivateKey(sk)
+	if err != nil {
+		return err
+	}
+	cfg.PrivateKey = nsec
+	if err := cfg.Save(cCtx.String("a")); err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, "privatekey is decrypted")
+	return nil
+}
@@ -7,7 +7,7 @@ import (
 	"github.com/mattn/algia/internal/domain"
 	"github.com/nbd-wtf/go-nostr"
 	"github.com/nbd-wtf/go-nostr/nip19"
-	"github.com/nbd-wtf/nostr-sdk"
+	"github.com/nbd-wtf/go-nostr/sdk"
 	"github.com/urfave/cli/v2"
 	"os"
 	"sync/atomic"
@@ -19,13 +19,11 @@ func DoLike(cCtx *cli.Context) error {
 	cfg := cCtx.App.Metadata["config"].(*domain.Config)
 
 	ev := nostr.Event{}
-	var sk string
-	if _, s, err := nip19.Decode(cfg.PrivateKey); err == nil {
-		sk = s.(string)
-	} else {
+	signer, err := cfg.Signer()
+	if err != nil {
 		return err
 	}
-	if pub, err := nostr.GetPublicKey(sk); err == nil {
+	if pub, err := signer.GetPublicKey(context.TODO()); err == nil {
 		if _, err := nip19.EncodePublicKey(pub); err != nil {
 			return err
 		}
@@ -60,25 +58,15 @@ func DoLike(cCtx *cli.Context) error {
 		ev.Content = "+"
 	}
 
-	var first atomic.Bool
-	first.Store(true)
+	for _, tmp := range cfg.Events(filter) {
+		ev.Tags = ev.Tags.AppendUnique(nostr.Tag{"p", tmp.PubKey})
+	}
+	if err := signer.SignEvent(context.TODO(), &ev); err != nil {
+		return err
+	}
 
 	var success atomic.Int64
 	cfg.Do(domain.Relay{Write: true}, func(ctx context.Context, relay *nostr.Relay) bool {
-		if first.Load() {
-			evs, err := relay.QuerySync(ctx, filter)
-			if err != nil {
-				return true
-			}
-			for _, tmp := range evs {
-				ev.Tags = ev.Tags.AppendUnique(nostr.Tag{"p", tmp.ID})
-			}
-			first.Store(false)
-			if err := ev.Sign(sk); err != nil {
-				return true
-			}
-			return true
-		}
 		err := relay.Publish(ctx, ev)
 		if err != nil {
 			fmt.Fprintln(os.Stderr, relay.URL, err)
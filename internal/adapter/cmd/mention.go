@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"github.com/mattn/algia/internal/domain"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/nbd-wtf/go-nostr/sdk"
+)
+
+// profileURI returns nostr: URI of the profile, nprofile if it has relay hints.
+func profileURI(pp *nostr.ProfilePointer) (string, error) {
+	var s string
+	var err error
+	if len(pp.Relays) > 0 {
+		s, err = nip19.EncodeProfile(pp.PublicKey, pp.Relays)
+	} else {
+		s, err = nip19.EncodePublicKey(pp.PublicKey)
+	}
+	if err != nil {
+		return "", err
+	}
+	return "nostr:" + s, nil
+}
+
+// resolveMention returns the profile of @name. The name is looked up in the
+// names and NIP-05 identifiers of follows, or resolved as npub or NIP-05.
+func resolveMention(followsMap map[string]domain.Profile, name string) (*nostr.ProfilePointer, error) {
+	if strings.ContainsAny(name, ".@") || strings.HasPrefix(name, "npub1") || strings.HasPrefix(name, "nprofile1") {
+		if pp := sdk.InputToProfile(context.TODO(), name); pp != nil {
+			return pp, nil
+		}
+		return nil, fmt.Errorf("cannot resolve @%s", name)
+	}
+
+	var found []string
+	for pub, profile := range followsMap {
+		local, _, _ := strings.Cut(profile.Nip05, "@")
+		if strings.EqualFold(profile.Name, name) || strings.EqualFold(profile.DisplayName, name) || strings.EqualFold(local, name) {
+			found = append(found, pub)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("cannot resolve @%s", name)
+	case 1:
+		return &nostr.ProfilePointer{PublicKey: found[0]}, nil
+	}
+	return nil, fmt.Errorf("@%s matches %d follows", name, len(found))
+}
+
+// mentionAfter returns whether @ after the character can be a mention. It is
+// not for e-mail addresses and paths in URLs.
+func mentionAfter(r rune) bool {
+	return r != '/' && r != '_' && !('0' <= r && r <= '9') && !('a' <= r && r <= 'z') && !('A' <= r && r <= 'Z')
+}
+
+// mentionUsers replaces @name in the content with NIP-27 references and
+// prepends references of the users. p tags are added for all of them.
+// Mentions which can not be resolved are left as is with a warning.
+func mentionUsers(cCtx *cli.Context, cfg *domain.Config, ev *nostr.Event, users []string) error {
+	mentions := extractMentions(ev.Content)
+	if len(mentions) > 0 {
+		followsMap, err := cfg.GetFollows(cCtx.String("a"))
+		if err != nil {
+			return err
+		}
+		runes := []rune(ev.Content)
+		for i := len(mentions) - 1; i >= 0; i-- {
+			m := mentions[i]
+			if m.start > 0 && !mentionAfter(runes[m.start-1]) {
+				continue
+			}
+			name := strings.TrimRight(m.text, ".")
+			pp, err := resolveMention(followsMap, name)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "warning:", err)
+				continue
+			}
+			uri, err := profileURI(pp)
+			if err != nil {
+				return err
+			}
+			end := m.start + 1 + int64(len([]rune(name)))
+			runes = append(runes[:m.start], append([]rune(uri), runes[end:]...)...)
+			ev.Tags = ev.Tags.AppendUnique(nostr.Tag{"p", pp.PublicKey})
+		}
+		ev.Content = string(runes)
+	}
+
+	var refs []string
+	for _, u := range users {
+		pp := sdk.InputToProfile(context.TODO(), u)
+		if pp == nil {
+			return fmt.Errorf("failed to parse pubkey from '%s'", u)
+		}
+		uri, err := profileURI(pp)
+		if err != nil {
+			return err
+		}
+		refs = append(refs, uri)
+		ev.Tags = ev.Tags.AppendUnique(nostr.Tag{"p", pp.PublicKey})
+	}
+	if len(refs) > 0 {
+		ev.Content = strings.Join(refs, " ") + " " + ev.Content
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/mattn/algia/internal/domain"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/urfave/cli/v2"
+)
+
+func TestMentionUsers(t *testing.T) {
+	sk := nostr.GeneratePrivateKey()
+	nsec, _ := nip19.EncodePrivateKey(sk)
+	pub, _ := nostr.GetPublicKey(nostr.GeneratePrivateKey())
+	npub, _ := nip19.EncodePublicKey(pub)
+	cfg := &domain.Config{
+		PrivateKey: nsec,
+		Offline:    true,
+		Follows:    map[string]domain.Profile{pub: {Name: "tester"}},
+	}
+	cCtx := cli.NewContext(cli.NewApp(), flag.NewFlagSet("post", flag.ContinueOnError), nil)
+
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{content: "@tester hi", want: "nostr:" + npub + " hi"},
+		{content: "hi (@tester)", want: "hi (nostr:" + npub + ")"},
+		{content: "こんにちは@tester", want: "こんにちは" + "nostr:" + npub},
+		{content: "見て、@tester。", want: "見て、" + "nostr:" + npub + "。"},
+		{content: "mail me a@tester", want: "mail me a@tester"},
+		{content: "mail me a_b@tester", want: "mail me a_b@tester"},
+		{content: "https://example.com/@tester", want: "https://example.com/@tester"},
+	}
+	for _, tt := range tests {
+		ev := &nostr.Event{Content: tt.content, Tags: nostr.Tags{}}
+		if err := mentionUsers(cCtx, cfg, ev, nil); err != nil {
+			t.Fatal(err)
+		}
+		if ev.Content != tt.want {
+			t.Errorf("%q: want %q, but got %q", tt.content, tt.want, ev.Content)
+		}
+		tagged := ev.Tags.GetFirst([]string{"p", pub}) != nil
+		if tagged != (tt.content != tt.want) {
+			t.Errorf("%q: want p tag only for mentions, but got %v", tt.content, ev.Tags)
+		}
+	}
+}
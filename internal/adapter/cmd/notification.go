@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/mattn/algia/internal/domain"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/urfave/cli/v2"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+)
+
+// notification is events of the same type on the same note.
+type notification struct {
+	Type   string         `json:"type"`
+	Target string         `json:"target,omitempty"`
+	Events []*nostr.Event `json:"events"`
+}
+
+// notificationOf returns the type and the target note of the event.
+func notificationOf(ev *nostr.Event) (string, string) {
+	var typ string
+	switch ev.Kind {
+	case nostr.KindTextNote:
+		if tag := immediateReply(ev.Tags); tag != nil {
+			return "reply", tag[1]
+		}
+		return "mention", ""
+	case nostr.KindRepost:
+		typ = "repost"
+	case nostr.KindReaction:
+		typ = "reaction"
+	case nostr.KindZap:
+		typ = "zap"
+	default:
+		return "", ""
+	}
+	if tag := ev.Tags.GetLast([]string{"e", ""}); tag != nil {
+		return typ, (*tag)[1]
+	}
+	return typ, ""
+}
+
+// zapRequest returns the zap request (kind 9734) in the zap receipt.
+func zapRequest(ev *nostr.Event) *nostr.Event {
+	tag := ev.Tags.GetFirst([]string{"description", ""})
+	if tag == nil {
+		return nil
+	}
+	var req nostr.Event
+	if err := json.Unmarshal([]byte((*tag)[1]), &req); err != nil {
+		return nil
+	}
+	return &req
+}
+
+// actorOf returns the public key of who did the event.
+func actorOf(ev *nostr.Event) string {
+	if ev.Kind == nostr.KindZap {
+		if req := zapRequest(ev); req != nil {
+			return req.PubKey
+		}
+	}
+	return ev.PubKey
+}
+
+func displayName(followsMap map[string]domain.Profile, pub string) string {
+	if profile, ok := followsMap[pub]; ok && profile.Name != "" {
+		return profile.Name
+	}
+	if npub, err := nip19.EncodePublicKey(pub); err == nil {
+		return npub
+	}
+	return pub
+}
+
+func DoNotifications(cCtx *cli.Context) error {
+	n := cCtx.Int("n")
+	j := cCtx.Bool("json")
+	unread := cCtx.Bool("unread")
+
+	cfg := cCtx.App.Metadata["config"].(*domain.Config)
+
+	pub, err := cfg.PublicKey()
+	if err != nil {
+		return err
+	}
+
+	// get followers
+	var followsMap map[string]domain.Profile
+	if j {
+		followsMap = make(map[string]domain.Profile)
+	} else {
+		followsMap, err = cfg.GetFollows(cCtx.String("a"))
+		if err != nil {
+			return err
+		}
+	}
+
+	filter := nostr.Filter{
+		Kinds: []int{nostr.KindTextNote, nostr.KindRepost, nostr.KindReaction, nostr.KindZap},
+		Tags:  nostr.TagMap{"p": []string{pub}},
+		Limit: n,
+	}
+	if err := setTimeRange(cCtx, &filter); err != nil {
+		return err
+	}
+	if unread && cfg.LastSeen > 0 {
+		since := cfg.LastSeen + 1
+		if filter.Since == nil || *filter.Since < since {
+			filter.Since = &since
+		}
+	}
+
+	var groups []*notification
+	index := map[string]*notification{}
+	var latest nostr.Timestamp
+	for _, ev := range cfg.CollectEvents(filter, n) {
+		if actorOf(ev) == pub {
+			continue
+		}
+		typ, target := notificationOf(ev)
+		if typ == "" {
+			continue
+		}
+		if ev.CreatedAt > latest {
+			latest = ev.CreatedAt
+		}
+		key := typ + ":" + target
+		g, ok := index[key]
+		if !ok {
+			g = &notification{Type: typ, Target: target}
+			index[key] = g
+			groups = append(groups, g)
+		}
+		g.Events = append(g.Events, ev)
+	}
+	// the group which has the newest event comes last
+	sort.SliceStable(groups, func(i, k int) bool {
+		return groups[i].Events[len(groups[i].Events)-1].CreatedAt < groups[k].Events[len(groups[k].Events)-1].CreatedAt
+	})
+
+	// JSON output and older pages do not mark newer ones as read
+	if latest > cfg.LastSeen && !cfg.Offline && !j && cCtx.String("until") == "" {
+		cfg.LastSeen = latest
+		if err := cfg.Save(cCtx.String("a")); err != nil {
+			return err
+		}
+	}
+
+	if j {
+		enc := json.NewEncoder(os.Stdout)
+		for _, g := range groups {
+			enc.Encode(g)
+		}
+		return nil
+	}
+
+	// get target notes to show what they are about
+	var ids []string
+	for _, g := range groups {
+		if g.Target != "" {
+			ids = append(ids, g.Target)
+		}
+	}
+	targets := map[string]*nostr.Event{}
+	if len(ids) > 0 {
+		for _, ev := range cfg.Events(nostr.Filter{IDs: ids}) {
+			targets[ev.ID] = ev
+		}
+	}
+
+	for _, g := range groups {
+		color.Set(color.FgHiYellow)
+		typ := g.Type
+		if len(g.Events) > 1 {
+			if typ == "reply" {
+				typ = "replies"
+			} else {
+				typ += "s"
+			}
+		}
+		fmt.Print(strconv.Itoa(len(g.Events)) + " " + typ)
+		if g.Target != "" {
+			fmt.Print(" to ")
+			if note, err := nip19.EncodeNote(g.Target); err == nil {
+				fmt.Print(note)
+			} else {
+				fmt.Print(g.Target)
+			}
+		}
+		color.Set(color.Reset)
+		fmt.Println()
+		if target, ok := targets[g.Target]; ok {
+			fmt.Println("  > " + summary(target.Content))
+		}
+		for _, ev := range g.Events {
+			switch g.Type {
+			case "reply", "mention":
+				cfg.PrintEvent(ev, followsMap, "  ")
+			case "zap":
+				var comment, amount string
+				if req := zapRequest(ev); req != nil {
+					comment = req.Content
+				}
+				if tag := ev.Tags.GetFirst([]string{"bolt11", ""}); tag != nil {
+					if msats, err := domain.Bolt11Amount((*tag)[1]); err == nil {
+						amount = fmt.Sprintf("%d sats ", msats/1000)
+					}
+				}
+				fmt.Printf("  %s: %s\n", displayName(followsMap, actorOf(ev)), strings.TrimSpace(amount+comment))
+			case "reaction":
+				fmt.Printf("  %s: %s\n", displayName(followsMap, ev.PubKey), ev.Content)
+			default:
+				fmt.Printf("  %s\n", displayName(followsMap, ev.PubKey))
+			}
+		}
+	}
+	return nil
+}
+
+// summary returns the first line of the content, truncated.
+func summary(content string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(content), "\n")
+	if r := []rune(line); len(r) > 60 {
+		line = string(r[:60]) + "..."
+	}
+	return line
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/mattn/algia/internal/domain"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// parseTimestamp parses unix time, RFC3339 or duration before now (e.g. 2h).
+func parseTimestamp(s string) (nostr.Timestamp, error) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return nostr.Timestamp(n), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return nostr.Timestamp(t.Unix()), nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return nostr.Timestamp(t.Unix()), nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return nostr.Timestamp(time.Now().Add(-d).Unix()), nil
+	}
+	return 0, fmt.Errorf("invalid time '%s'", s)
+}
+
+// setTimeRange sets since and until of the filter from the flags.
+func setTimeRange(cCtx *cli.Context, filter *nostr.Filter) error {
+	if s := cCtx.String("since"); s != "" {
+		since, err := parseTimestamp(s)
+		if err != nil {
+			return err
+		}
+		filter.Since = &since
+	}
+	if s := cCtx.String("until"); s != "" {
+		until, err := parseTimestamp(s)
+		if err != nil {
+			return err
+		}
+		filter.Until = &until
+	}
+	return nil
+}
+
+// pageCursor is the position to continue the page: the oldest created_at and
+// the prefixes of ids already shown at the time, since relays may have more
+// events at the same second.
+type pageCursor struct {
+	until nostr.Timestamp
+	seen  []string
+}
+
+func parseCursor(s string) (*pageCursor, error) {
+	ts, ids, _ := strings.Cut(s, ":")
+	n, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor '%s'", s)
+	}
+	c := &pageCursor{until: nostr.Timestamp(n)}
+	if ids != "" {
+		c.seen = strings.Split(ids, ",")
+	}
+	return c, nil
+}
+
+func (c *pageCursor) String() string {
+	return fmt.Sprintf("%d:%s", c.until, strings.Join(c.seen, ","))
+}
+
+func (c *pageCursor) shown(ev *nostr.Event) bool {
+	if ev.CreatedAt != c.until {
+		return false
+	}
+	for _, id := range c.seen {
+		if strings.HasPrefix(ev.ID, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// collectPage is CollectEvents which continues from the cursor of --cursor.
+func collectPage(cfg *domain.Config, filter nostr.Filter, n int, s string) ([]*nostr.Event, error) {
+	if s == "" {
+		return cfg.CollectEvents(filter, n), nil
+	}
+	c, err := parseCursor(s)
+	if err != nil {
+		return nil, err
+	}
+	if filter.Until == nil || *filter.Until > c.until {
+		filter.Until = &c.until
+	}
+	// until is inclusive, get the shown ones too and drop them
+	filter.Limit = n + len(c.seen)
+	var evs []*nostr.Event
+	for _, ev := range cfg.CollectEvents(filter, filter.Limit) {
+		if !c.shown(ev) {
+			evs = append(evs, ev)
+		}
+	}
+	if len(evs) > n {
+		evs = evs[len(evs)-n:]
+	}
+	return evs, nil
+}
+
+// nextCursor returns the cursor after the events of the page which started
+// from the previous cursor, or nil when there are no more events.
+func nextCursor(prev string, evs []*nostr.Event) *pageCursor {
+	if len(evs) == 0 {
+		return nil
+	}
+	c := &pageCursor{until: evs[0].CreatedAt}
+	// the ones shown in the previous pages at the same second
+	if p, err := parseCursor(prev); err == nil && p.until == c.until {
+		c.seen = p.seen
+	}
+	for _, ev := range evs {
+		if ev.CreatedAt != c.until {
+			break
+		}
+		c.seen = append(c.seen, ev.ID[:8])
+	}
+	return c
+}
+
+// printCursor prints the cursor to continue with --cursor. Nothing is printed
+// when there are no more events.
+func printCursor(cCtx *cli.Context, evs []*nostr.Event) {
+	if c := nextCursor(cCtx.String("cursor"), evs); c != nil {
+		fmt.Fprintf(os.Stderr, "next: --cursor %s\n", c)
+	}
+}
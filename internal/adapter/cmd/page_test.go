@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/mattn/algia/internal/domain"
+	"github.com/mattn/algia/internal/testrelay"
+	"github.com/nbd-wtf/go-nostr"
+)
+
+func TestParseCursor(t *testing.T) {
+	tests := []struct {
+		input string
+		want  *pageCursor
+	}{
+		{input: "1700000400", want: &pageCursor{until: 1700000400}},
+		{input: "1700000400:", want: &pageCursor{until: 1700000400}},
+		{input: "1700000400:1d2f9a7c", want: &pageCursor{until: 1700000400, seen: []string{"1d2f9a7c"}}},
+		{input: "1700000400:1d2f9a7c,a4d70f79", want: &pageCursor{until: 1700000400, seen: []string{"1d2f9a7c", "a4d70f79"}}},
+		{input: "", want: nil},
+		{input: "yesterday:1d2f9a7c", want: nil},
+	}
+	for _, tt := range tests {
+		got, err := parseCursor(tt.input)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("%q: want error, but got %v", tt.input, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: want %+v, but got %+v", tt.input, tt.want, got)
+		}
+		// it can be given again
+		if again, err := parseCursor(got.String()); err != nil || !reflect.DeepEqual(again, got) {
+			t.Errorf("%q: want %+v from %q, but got %+v: %v", tt.input, got, got.String(), again, err)
+		}
+	}
+}
+
+func TestCollectPageSameSecond(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	r := testrelay.New()
+	defer r.Close()
+
+	ctx := context.Background()
+	relay, err := nostr.RelayConnect(ctx, r.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer relay.Close()
+
+	// a burst at the same second between others
+	sk := nostr.GeneratePrivateKey()
+	now := nostr.Now()
+	times := []nostr.Timestamp{now + 5, now, now, now, now, now, now - 10, now - 10}
+	want := map[string]bool{}
+	for i, ts := range times {
+		ev := nostr.Event{Kind: nostr.KindTextNote, CreatedAt: ts, Tags: nostr.Tags{}, Content: fmt.Sprint(i)}
+		if err := ev.Sign(sk); err != nil {
+			t.Fatal(err)
+		}
+		if err := relay.Publish(ctx, ev); err != nil {
+			t.Fatal(err)
+		}
+		want[ev.ID] = true
+	}
+
+	cfg := &domain.Config{Relays: map[string]domain.Relay{r.URL: {Read: true, Write: true}}}
+	defer cfg.Close()
+	filter := nostr.Filter{Kinds: []int{nostr.KindTextNote}}
+	got := map[string]bool{}
+	var cursor string
+	for page := 0; page < len(times); page++ {
+		evs, err := collectPage(cfg, filter, 2, cursor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(evs) == 0 {
+			break
+		}
+		for _, ev := range evs {
+			if got[ev.ID] {
+				t.Fatalf("page %d: %q is shown again with cursor %q", page, ev.Content, cursor)
+			}
+			got[ev.ID] = true
+		}
+		cursor = nextCursor(cursor, evs).String()
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("want %d events, but got %d", len(want), len(got))
+	}
+}
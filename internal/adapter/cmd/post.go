@@ -7,7 +7,6 @@ import (
 	"github.com/mattn/algia/internal/domain"
 	"github.com/nbd-wtf/go-nostr"
 	"github.com/nbd-wtf/go-nostr/nip19"
-	"github.com/nbd-wtf/nostr-sdk"
 	"github.com/urfave/cli/v2"
 	"io"
 	"os"
@@ -16,79 +15,109 @@ import (
 )
 
 func DoPost(cCtx *cli.Context) error {
+	var at nostr.Timestamp
+	if s := cCtx.String("at"); s != "" {
+		var err error
+		if at, err = parseScheduleTime(s); err != nil {
+			return err
+		}
+	}
+
+	cfg := cCtx.App.Metadata["config"].(*domain.Config)
+
+	ev, err := composeNote(cCtx, cfg)
+	if err != nil || ev == nil {
+		return err
+	}
+	if err := completeNote(cCtx, cfg, ev, cCtx.StringSlice("u")); err != nil {
+		return err
+	}
+	if cCtx.Bool("edit") {
+		if ok, err := confirmEvent(ev); err != nil {
+			return err
+		} else if !ok {
+			fmt.Fprintln(os.Stderr, "aborted")
+			return nil
+		}
+	}
+	if at != 0 {
+		return scheduleNote(cfg, ev, at)
+	}
+	return publishNote(cfg, ev)
+}
+
+// composeNote returns the note with the content and the tags given by flags.
+// nil is returned when nothing should be posted.
+func composeNote(cCtx *cli.Context, cfg *domain.Config) (*nostr.Event, error) {
 	stdin := cCtx.Bool("stdin")
-	if !stdin && cCtx.Args().Len() == 0 {
-		return cli.ShowSubcommandHelp(cCtx)
+	edit := cCtx.Bool("edit")
+	if !stdin && !edit && cCtx.Args().Len() == 0 {
+		return nil, cli.ShowSubcommandHelp(cCtx)
+	}
+	if stdin && edit {
+		return nil, errors.New("--edit can not be used with --stdin")
 	}
 	sensitive := cCtx.String("sensitive")
 	geohash := cCtx.String("geohash")
 	articleName := cCtx.String("article-name")
 	articleTitle := cCtx.String("article-title")
 	articleSummary := cCtx.String("article-summary")
-	if articleName != "" && articleTitle == "" {
-		return cli.ShowSubcommandHelp(cCtx)
+	if articleName != "" && articleTitle == "" && !edit {
+		return nil, cli.ShowSubcommandHelp(cCtx)
 	}
 
-	cfg := cCtx.App.Metadata["config"].(*domain.Config)
-
-	var sk string
-	if _, s, err := nip19.Decode(cfg.PrivateKey); err == nil {
-		sk = s.(string)
-	} else {
-		return err
-	}
 	ev := nostr.Event{}
-	if pub, err := nostr.GetPublicKey(sk); err == nil {
-		if _, err := nip19.EncodePublicKey(pub); err != nil {
-			return err
-		}
-		ev.PubKey = pub
-	} else {
-		return err
+	pub, err := cfg.PublicKey()
+	if err != nil {
+		return nil, err
 	}
+	ev.PubKey = pub
 
 	if stdin {
 		b, err := io.ReadAll(os.Stdin)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		ev.Content = string(b)
 	} else {
 		ev.Content = strings.Join(cCtx.Args().Slice(), "\n")
 	}
+	if edit {
+		text := ev.Content
+		if articleName != "" {
+			text = fmt.Sprintf("---\ntitle: %s\nsummary: %s\n---\n\n%s", articleTitle, articleSummary, text)
+		}
+		content, err := editContent(text, "")
+		if err != nil {
+			return nil, err
+		}
+		if content == "" {
+			fmt.Fprintln(os.Stderr, "aborted: empty note")
+			return nil, nil
+		}
+		if articleName != "" {
+			var values map[string]string
+			values, content = splitFrontMatter(content)
+			articleTitle, articleSummary = values["title"], values["summary"]
+			if articleTitle == "" {
+				return nil, errors.New("title is empty")
+			}
+		}
+		ev.Content = content
+	}
 	if strings.TrimSpace(ev.Content) == "" {
-		return errors.New("content is empty")
+		return nil, errors.New("content is empty")
 	}
 
 	ev.Tags = nostr.Tags{}
 
-	for _, entry := range extractLinks(ev.Content) {
-		ev.Tags = ev.Tags.AppendUnique(nostr.Tag{"r", entry.text})
-	}
-
 	for _, u := range cCtx.StringSlice("emoji") {
 		tok := strings.SplitN(u, "=", 2)
 		if len(tok) != 2 {
-			return cli.ShowSubcommandHelp(cCtx)
+			return nil, cli.ShowSubcommandHelp(cCtx)
 		}
 		ev.Tags = ev.Tags.AppendUnique(nostr.Tag{"emoji", tok[0], tok[1]})
 	}
-	for _, entry := range extractEmojis(ev.Content) {
-		name := strings.Trim(entry.text, ":")
-		if icon, ok := cfg.Emojis[name]; ok {
-			ev.Tags = ev.Tags.AppendUnique(nostr.Tag{"emoji", name, icon})
-		}
-	}
-
-	for i, u := range cCtx.StringSlice("u") {
-		ev.Content = fmt.Sprintf("#[%d] ", i) + ev.Content
-		if pp := sdk.InputToProfile(context.TODO(), u); pp != nil {
-			u = pp.PublicKey
-		} else {
-			return fmt.Errorf("failed to parse pubkey from '%s'", u)
-		}
-		ev.Tags = ev.Tags.AppendUnique(nostr.Tag{"p", u})
-	}
 
 	if sensitive != "" {
 		ev.Tags = ev.Tags.AppendUnique(nostr.Tag{"content-warning", sensitive})
@@ -98,32 +127,81 @@ func DoPost(cCtx *cli.Context) error {
 		ev.Tags = ev.Tags.AppendUnique(nostr.Tag{"g", geohash})
 	}
 
-	hashtag := nostr.Tag{"t"}
-	for _, m := range extractTags(ev.Content) {
-		hashtag = append(hashtag, m.text)
-	}
-	if len(hashtag) > 1 {
-		ev.Tags = ev.Tags.AppendUnique(hashtag)
-	}
-
 	ev.CreatedAt = nostr.Now()
 	if articleName != "" {
 		ev.Kind = nostr.KindArticle
 		ev.Tags = ev.Tags.AppendUnique(nostr.Tag{"d", articleName})
 		ev.Tags = ev.Tags.AppendUnique(nostr.Tag{"title", articleTitle})
 		ev.Tags = ev.Tags.AppendUnique(nostr.Tag{"summary", articleSummary})
-		ev.Tags = ev.Tags.AppendUnique(nostr.Tag{"published_at", fmt.Sprint(nostr.Now())})
 		ev.Tags = ev.Tags.AppendUnique(nostr.Tag{"a", fmt.Sprintf("%d:%s:%s", ev.Kind, ev.PubKey, articleName), "wss://yabu.me"})
 	} else {
 		ev.Kind = nostr.KindTextNote
 	}
-	if err := ev.Sign(sk); err != nil {
+	return &ev, nil
+}
+
+// completeNote adds tags for links, emojis, mentions and hashtags in the
+// content, and mentions the users.
+func completeNote(cCtx *cli.Context, cfg *domain.Config, ev *nostr.Event, users []string) error {
+	for _, entry := range extractLinks(ev.Content) {
+		ev.Tags = ev.Tags.AppendUnique(nostr.Tag{"r", entry.text})
+	}
+
+	for _, entry := range extractEmojis(ev.Content) {
+		name := strings.Trim(entry.text, ":")
+		if icon, ok := cfg.Emojis[name]; ok {
+			ev.Tags = ev.Tags.AppendUnique(nostr.Tag{"emoji", name, icon})
+		}
+	}
+
+	if err := mentionUsers(cCtx, cfg, ev, users); err != nil {
+		return err
+	}
+
+	hashtag := nostr.Tag{"t"}
+	for _, m := range extractTags(ev.Content) {
+		hashtag = append(hashtag, m.text)
+	}
+	if len(hashtag) > 1 {
+		ev.Tags = ev.Tags.AppendUnique(hashtag)
+	}
+	return nil
+}
+
+// signNote signs the note created at the time.
+func signNote(cfg *domain.Config, ev *nostr.Event, at nostr.Timestamp) error {
+	signer, err := cfg.Signer()
+	if err != nil {
 		return err
 	}
+	ev.CreatedAt = at
+	if ev.Kind == nostr.KindArticle && ev.Tags.GetFirst([]string{"published_at"}) == nil {
+		ev.Tags = ev.Tags.AppendUnique(nostr.Tag{"published_at", fmt.Sprint(at)})
+	}
+	return signer.SignEvent(context.TODO(), ev)
+}
 
+// publishNote signs the note and publishes it to the write relays.
+func publishNote(cfg *domain.Config, ev *nostr.Event) error {
+	if err := signNote(cfg, ev, nostr.Now()); err != nil {
+		return err
+	}
+	if err := publishEvent(cfg, ev); err != nil {
+		return err
+	}
+	if cfg.Verbose {
+		if id, err := nip19.EncodeNote(ev.ID); err == nil {
+			fmt.Println(id)
+		}
+	}
+	return nil
+}
+
+// publishEvent publishes the signed event to the write relays.
+func publishEvent(cfg *domain.Config, ev *nostr.Event) error {
 	var success atomic.Int64
 	cfg.Do(domain.Relay{Write: true}, func(ctx context.Context, relay *nostr.Relay) bool {
-		err := relay.Publish(ctx, ev)
+		err := relay.Publish(ctx, *ev)
 		if err != nil {
 			fmt.Fprintln(os.Stderr, relay.URL, err)
 		} else {
@@ -134,10 +212,5 @@ func DoPost(cCtx *cli.Context) error {
 	if success.Load() == 0 {
 		return errors.New("cannot post")
 	}
-	if cfg.Verbose {
-		if id, err := nip19.EncodeNote(ev.ID); err == nil {
-			fmt.Println(id)
-		}
-	}
 	return nil
 }
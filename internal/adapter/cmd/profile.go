@@ -6,13 +6,15 @@ import (
 	"errors"
 	"fmt"
 	"github.com/mattn/algia/internal/domain"
+	"io"
 	"os"
+	"sync/atomic"
 
 	"github.com/urfave/cli/v2"
 
 	"github.com/nbd-wtf/go-nostr"
 	"github.com/nbd-wtf/go-nostr/nip19"
-	"github.com/nbd-wtf/nostr-sdk"
+	"github.com/nbd-wtf/go-nostr/sdk"
 )
 
 func DoProfile(cCtx *cli.Context) error {
@@ -20,19 +22,18 @@ func DoProfile(cCtx *cli.Context) error {
 	j := cCtx.Bool("json")
 
 	cfg := cCtx.App.Metadata["config"].(*domain.Config)
-	relay := cfg.FindRelay(context.Background(), domain.Relay{Read: true})
-	if relay == nil {
-		return errors.New("cannot connect relays")
+	if !cfg.Offline {
+		relay := cfg.FindRelay(context.Background(), domain.Relay{Read: true})
+		if relay == nil {
+			return errors.New("cannot connect relays")
+		}
+		relay.Close()
 	}
-	defer relay.Close()
 
 	var pub string
 	if user == "" {
-		if _, s, err := nip19.Decode(cfg.PrivateKey); err == nil {
-			if pub, err = nostr.GetPublicKey(s.(string)); err != nil {
-				return err
-			}
-		} else {
+		var err error
+		if pub, err = cfg.PublicKey(); err != nil {
 			return err
 		}
 	} else {
@@ -78,3 +79,151 @@ func DoProfile(cCtx *cli.Context) error {
 	fmt.Printf("About: %v\n", profile.About)
 	return nil
 }
+
+// profileFields maps profile-set flags to kind 0 metadata keys.
+var profileFields = []struct {
+	flag string
+	key  string
+}{
+	{"name", "name"},
+	{"display-name", "display_name"},
+	{"about", "about"},
+	{"picture", "picture"},
+	{"banner", "banner"},
+	{"website", "website"},
+	{"nip05", "nip05"},
+	{"lud16", "lud16"},
+	{"lud06", "lud06"},
+}
+
+// profileFlags returns the profile fields given by flags, keyed by kind 0
+// metadata keys.
+func profileFlags(cCtx *cli.Context) map[string]string {
+	fields := map[string]string{}
+	for _, f := range profileFields {
+		if cCtx.IsSet(f.flag) {
+			fields[f.key] = cCtx.String(f.flag)
+		}
+	}
+	return fields
+}
+
+// currentProfile returns the latest kind 0 of the user, or nil.
+func currentProfile(cfg *domain.Config, pub string) *nostr.Event {
+	var old *nostr.Event
+	for _, ev := range cfg.Events(nostr.Filter{
+		Kinds:   []int{nostr.KindProfileMetadata},
+		Authors: []string{pub},
+		Limit:   1,
+	}) {
+		if old == nil || ev.CreatedAt > old.CreatedAt {
+			old = ev
+		}
+	}
+	return old
+}
+
+// setProfile publishes kind 0 metadata with the fields. Empty values remove
+// the keys. The fields are merged into the current profile, which must exist
+// unless newProfile is set (e.g. for a key just generated).
+func setProfile(cfg *domain.Config, fields map[string]string, newProfile bool) error {
+	pub, err := cfg.PublicKey()
+	if err != nil {
+		return err
+	}
+	metadata := map[string]any{}
+	old := currentProfile(cfg, pub)
+	// refuse to overwrite the profile with only the given fields
+	if old == nil && !newProfile {
+		return errors.New("cannot find current profile (use --force to create a new one)")
+	}
+	if old != nil {
+		if err := json.Unmarshal([]byte(old.Content), &metadata); err != nil {
+			return fmt.Errorf("cannot parse current profile: %w", err)
+		}
+	}
+	for k, v := range fields {
+		if v != "" {
+			metadata[k] = v
+		} else {
+			delete(metadata, k)
+		}
+	}
+	return publishProfile(cfg, metadata, old)
+}
+
+// publishProfile publishes the metadata keeping the tags of the old profile.
+func publishProfile(cfg *domain.Config, metadata map[string]any, old *nostr.Event) error {
+	signer, err := cfg.Signer()
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	ev := nostr.Event{}
+	if ev.PubKey, err = signer.GetPublicKey(context.TODO()); err != nil {
+		return err
+	}
+	ev.Content = string(b)
+	ev.CreatedAt = nostr.Now()
+	ev.Kind = nostr.KindProfileMetadata
+	ev.Tags = nostr.Tags{}
+	if old != nil {
+		ev.Tags = old.Tags
+	}
+	if err := signer.SignEvent(context.TODO(), &ev); err != nil {
+		return err
+	}
+
+	var success atomic.Int64
+	cfg.Do(domain.Relay{Write: true}, func(ctx context.Context, relay *nostr.Relay) bool {
+		err := relay.Publish(ctx, ev)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, relay.URL, err)
+		} else {
+			success.Add(1)
+		}
+		return true
+	})
+	if success.Load() == 0 {
+		return errors.New("cannot post profile")
+	}
+	return nil
+}
+
+func DoProfileSet(cCtx *cli.Context) error {
+	fromFile := cCtx.String("from-file")
+	force := cCtx.Bool("force")
+
+	cfg := cCtx.App.Metadata["config"].(*domain.Config)
+
+	if fromFile == "" {
+		fields := profileFlags(cCtx)
+		if len(fields) == 0 {
+			return cli.ShowSubcommandHelp(cCtx)
+		}
+		return setProfile(cfg, fields, force)
+	}
+
+	var b []byte
+	var err error
+	if fromFile == "-" {
+		b, err = io.ReadAll(os.Stdin)
+	} else {
+		b, err = os.ReadFile(fromFile)
+	}
+	if err != nil {
+		return err
+	}
+	metadata := map[string]any{}
+	if err := json.Unmarshal(b, &metadata); err != nil {
+		return fmt.Errorf("invalid profile JSON: %w", err)
+	}
+	pub, err := cfg.PublicKey()
+	if err != nil {
+		return err
+	}
+	return publishProfile(cfg, metadata, currentProfile(cfg, pub))
+}
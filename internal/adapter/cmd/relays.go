@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mattn/algia/internal/domain"
+	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/fatih/color"
+	"github.com/nbd-wtf/go-nostr"
+)
+
+func DoRelays(cCtx *cli.Context) error {
+	j := cCtx.Bool("json")
+
+	cfg := cCtx.App.Metadata["config"].(*domain.Config)
+
+	pub, err := cfg.PublicKey()
+	if err != nil {
+		return err
+	}
+
+	type entry struct {
+		URL    string `json:"url"`
+		Read   bool   `json:"read"`
+		Write  bool   `json:"write"`
+		Search bool   `json:"search"`
+		Config bool   `json:"config"`
+		List   bool   `json:"list"`
+	}
+	m := map[string]*entry{}
+	for k, v := range cfg.Relays {
+		m[nostr.NormalizeURL(k)] = &entry{
+			URL:    k,
+			Read:   v.Read,
+			Write:  v.Write,
+			Search: v.Search,
+			Config: true,
+		}
+	}
+	rm, _ := cfg.GetRelayList(pub)
+	for k, v := range rm {
+		if e, ok := m[k]; ok {
+			e.List = true
+			continue
+		}
+		m[k] = &entry{
+			URL:   k,
+			Read:  v.Read,
+			Write: v.Write,
+			List:  true,
+		}
+	}
+
+	entries := []*entry{}
+	for _, e := range m {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].URL < entries[j].URL
+	})
+
+	if j {
+		for _, e := range entries {
+			json.NewEncoder(os.Stdout).Encode(e)
+		}
+		return nil
+	}
+
+	for _, e := range entries {
+		var flags []string
+		if e.Read {
+			flags = append(flags, "read")
+		}
+		if e.Write {
+			flags = append(flags, "write")
+		}
+		if e.Search {
+			flags = append(flags, "search")
+		}
+		var sources []string
+		if e.Config {
+			sources = append(sources, "config")
+		}
+		if e.List {
+			sources = append(sources, "list")
+		}
+		color.Set(color.FgHiBlue)
+		fmt.Print(e.URL)
+		color.Set(color.Reset)
+		fmt.Printf(": %s (%s)\n", strings.Join(flags, ","), strings.Join(sources, ","))
+	}
+	return nil
+}
+
+func DoRelaysAdd(cCtx *cli.Context) error {
+	if cCtx.Args().Len() == 0 {
+		return cli.ShowSubcommandHelp(cCtx)
+	}
+
+	cfg := cCtx.App.Metadata["config"].(*domain.Config)
+	if cfg.TempRelay {
+		return errors.New("cannot modify relays with --relays")
+	}
+
+	r := domain.Relay{
+		Read:   cCtx.Bool("read"),
+		Write:  cCtx.Bool("write"),
+		Search: cCtx.Bool("search"),
+	}
+	for _, u := range cCtx.Args().Slice() {
+		if !nostr.IsValidRelayURL(u) {
+			return fmt.Errorf("invalid relay URL '%s'", u)
+		}
+		cfg.AddRelay(u, r)
+	}
+	if err := cfg.Save(cCtx.String("a")); err != nil {
+		return err
+	}
+	if cCtx.Bool("publish") {
+		return DoRelaysPublish(cCtx)
+	}
+	return nil
+}
+
+func DoRelaysRemove(cCtx *cli.Context) error {
+	if cCtx.Args().Len() == 0 {
+		return cli.ShowSubcommandHelp(cCtx)
+	}
+
+	cfg := cCtx.App.Metadata["config"].(*domain.Config)
+	if cfg.TempRelay {
+		return errors.New("cannot modify relays with --relays")
+	}
+
+	for _, u := range cCtx.Args().Slice() {
+		if !cfg.RemoveRelay(u) {
+			return fmt.Errorf("relay '%s' is not configured", u)
+		}
+	}
+	if len(cfg.Relays) == 0 {
+		return errors.New("cannot remove all relays")
+	}
+	if err := cfg.Save(cCtx.String("a")); err != nil {
+		return err
+	}
+	if cCtx.Bool("publish") {
+		return DoRelaysPublish(cCtx)
+	}
+	return nil
+}
+
+func DoRelaysPublish(cCtx *cli.Context) error {
+	cfg := cCtx.App.Metadata["config"].(*domain.Config)
+
+	return publishRelayList(cfg)
+}
+
+// publishRelayList publishes the read/write relays as NIP-65 relay list.
+func publishRelayList(cfg *domain.Config) error {
+	signer, err := cfg.Signer()
+	if err != nil {
+		return err
+	}
+	ev := nostr.Event{}
+	if pub, err := signer.GetPublicKey(context.TODO()); err == nil {
+		ev.PubKey = pub
+	} else {
+		return err
+	}
+
+	ev.Tags = domain.RelayListTags(cfg.Relays)
+	if len(ev.Tags) == 0 {
+		return errors.New("no read/write relays to publish")
+	}
+	ev.CreatedAt = nostr.Now()
+	ev.Kind = nostr.KindRelayListMetadata
+	if err := signer.SignEvent(context.TODO(), &ev); err != nil {
+		return err
+	}
+
+	var success atomic.Int64
+	cfg.Do(domain.Relay{Write: true}, func(ctx context.Context, relay *nostr.Relay) bool {
+		err := relay.Publish(ctx, ev)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, relay.URL, err)
+		} else {
+			success.Add(1)
+		}
+		return true
+	})
+	if success.Load() == 0 {
+		return errors.New("cannot publish relay list")
+	}
+	return nil
+}
@@ -7,7 +7,7 @@ import (
 	"github.com/mattn/algia/internal/domain"
 	"github.com/nbd-wtf/go-nostr"
 	"github.com/nbd-wtf/go-nostr/nip19"
-	"github.com/nbd-wtf/nostr-sdk"
+	"github.com/nbd-wtf/go-nostr/sdk"
 	"github.com/urfave/cli/v2"
 	"io"
 	"os"
@@ -16,25 +16,35 @@ import (
 )
 
 func DoReply(cCtx *cli.Context) error {
+	return postReply(cCtx, cCtx.Bool("quote"))
+}
+
+func DoQuote(cCtx *cli.Context) error {
+	return postReply(cCtx, true)
+}
+
+// postReply posts a reply to the note, or a note which quotes it (NIP-18).
+func postReply(cCtx *cli.Context, quote bool) error {
 	stdin := cCtx.Bool("stdin")
+	edit := cCtx.Bool("edit")
 	id := cCtx.String("id")
-	quote := cCtx.Bool("quote")
-	if !stdin && cCtx.Args().Len() == 0 {
+	if !stdin && !edit && cCtx.Args().Len() == 0 {
 		return cli.ShowSubcommandHelp(cCtx)
 	}
+	if stdin && edit {
+		return errors.New("--edit can not be used with --stdin")
+	}
 	sensitive := cCtx.String("sensitive")
 	geohash := cCtx.String("geohash")
 
 	cfg := cCtx.App.Metadata["config"].(*domain.Config)
 
-	var sk string
-	if _, s, err := nip19.Decode(cfg.PrivateKey); err == nil {
-		sk = s.(string)
-	} else {
+	signer, err := cfg.Signer()
+	if err != nil {
 		return err
 	}
 	ev := nostr.Event{}
-	if pub, err := nostr.GetPublicKey(sk); err == nil {
+	if pub, err := signer.GetPublicKey(context.TODO()); err == nil {
 		if _, err := nip19.EncodePublicKey(pub); err != nil {
 			return err
 		}
@@ -43,11 +53,20 @@ func DoReply(cCtx *cli.Context) error {
 		return err
 	}
 
+	var hint string
 	if evp := sdk.InputToEventPointer(id); evp != nil {
 		id = evp.ID
+		if len(evp.Relays) > 0 {
+			hint = evp.Relays[0]
+		}
 	} else {
 		return fmt.Errorf("failed to parse event from '%s'", id)
 	}
+	evs := cfg.Events(nostr.Filter{IDs: []string{id}})
+	if len(evs) == 0 {
+		return fmt.Errorf("failed to get event '%s'", id)
+	}
+	parent := evs[0]
 
 	ev.CreatedAt = nostr.Now()
 	ev.Kind = nostr.KindTextNote
@@ -60,6 +79,17 @@ func DoReply(cCtx *cli.Context) error {
 	} else {
 		ev.Content = strings.Join(cCtx.Args().Slice(), "\n")
 	}
+	if edit {
+		content, err := editContent(ev.Content, quoteComment(parent))
+		if err != nil {
+			return err
+		}
+		if content == "" {
+			fmt.Fprintln(os.Stderr, "aborted: empty note")
+			return nil
+		}
+		ev.Content = content
+	}
 	if strings.TrimSpace(ev.Content) == "" {
 		return errors.New("content is empty")
 	}
@@ -84,6 +114,10 @@ func DoReply(cCtx *cli.Context) error {
 		}
 	}
 
+	if err := mentionUsers(cCtx, cfg, &ev, cCtx.StringSlice("u")); err != nil {
+		return err
+	}
+
 	if sensitive != "" {
 		ev.Tags = ev.Tags.AppendUnique(nostr.Tag{"content-warning", sensitive})
 	}
@@ -100,16 +134,52 @@ func DoReply(cCtx *cli.Context) error {
 		ev.Tags = ev.Tags.AppendUnique(hashtag)
 	}
 
-	var success atomic.Int64
-	cfg.Do(domain.Relay{Write: true}, func(ctx context.Context, relay *nostr.Relay) bool {
-		if !quote {
-			ev.Tags = ev.Tags.AppendUnique(nostr.Tag{"e", id, relay.URL, "reply"})
+	if !quote {
+		if root := threadRoot(parent.Tags); root != nil {
+			rootHint := ""
+			if len(root) >= 3 {
+				rootHint = root[2]
+			}
+			ev.Tags = ev.Tags.AppendUnique(nostr.Tag{"e", root[1], rootHint, "root"})
+			ev.Tags = ev.Tags.AppendUnique(nostr.Tag{"e", parent.ID, hint, "reply"})
 		} else {
-			ev.Tags = ev.Tags.AppendUnique(nostr.Tag{"e", id, relay.URL, "mention"})
+			ev.Tags = ev.Tags.AppendUnique(nostr.Tag{"e", parent.ID, hint, "root"})
 		}
-		if err := ev.Sign(sk); err != nil {
-			return true
+		for _, tag := range parent.Tags {
+			if len(tag) >= 2 && tag[0] == "p" && tag[1] != ev.PubKey {
+				ev.Tags = ev.Tags.AppendUnique(nostr.Tag{"p", tag[1]})
+			}
 		}
+	} else {
+		var relays []string
+		if hint != "" {
+			relays = []string{hint}
+		}
+		nevent, err := encodeEvent(parent.ID, relays, parent.PubKey, parent.Kind)
+		if err != nil {
+			return err
+		}
+		ev.Content = strings.TrimRight(ev.Content, "\n") + "\nnostr:" + nevent
+		ev.Tags = ev.Tags.AppendUnique(nostr.Tag{"q", parent.ID, hint, parent.PubKey})
+	}
+	if parent.PubKey != ev.PubKey {
+		ev.Tags = ev.Tags.AppendUnique(nostr.Tag{"p", parent.PubKey})
+	}
+
+	if edit {
+		if ok, err := confirmEvent(&ev); err != nil {
+			return err
+		} else if !ok {
+			fmt.Fprintln(os.Stderr, "aborted")
+			return nil
+		}
+	}
+	if err := signer.SignEvent(context.TODO(), &ev); err != nil {
+		return err
+	}
+
+	var success atomic.Int64
+	cfg.Do(domain.Relay{Write: true}, func(ctx context.Context, relay *nostr.Relay) bool {
 		err := relay.Publish(ctx, ev)
 		if err != nil {
 			fmt.Fprintln(os.Stderr, relay.URL, err)
@@ -123,3 +193,34 @@ func DoReply(cCtx *cli.Context) error {
 	}
 	return nil
 }
+
+// threadRoot returns the root "e" tag of the thread, or nil if the event is
+// the root itself. Positional "e" tags of old clients are also supported, and
+// the "reply" one is taken as the root when there is no "root" marker, since
+// older algia marked only the parent with "reply".
+func threadRoot(tags nostr.Tags) nostr.Tag {
+	var first, reply nostr.Tag
+	for _, tag := range tags {
+		if len(tag) < 2 || tag[0] != "e" {
+			continue
+		}
+		if len(tag) >= 4 && tag[3] != "" {
+			switch tag[3] {
+			case "root":
+				return tag
+			case "reply":
+				if reply == nil {
+					reply = tag
+				}
+			}
+			continue
+		}
+		if first == nil {
+			first = tag
+		}
+	}
+	if first != nil {
+		return first
+	}
+	return reply
+}
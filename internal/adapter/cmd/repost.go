@@ -7,9 +7,10 @@ import (
 	"github.com/mattn/algia/internal/domain"
 	"github.com/nbd-wtf/go-nostr"
 	"github.com/nbd-wtf/go-nostr/nip19"
-	"github.com/nbd-wtf/nostr-sdk"
+	"github.com/nbd-wtf/go-nostr/sdk"
 	"github.com/urfave/cli/v2"
 	"os"
+	"strconv"
 	"sync/atomic"
 )
 
@@ -19,13 +20,11 @@ func DoRepost(cCtx *cli.Context) error {
 	cfg := cCtx.App.Metadata["config"].(*domain.Config)
 
 	ev := nostr.Event{}
-	var sk string
-	if _, s, err := nip19.Decode(cfg.PrivateKey); err == nil {
-		sk = s.(string)
-	} else {
+	signer, err := cfg.Signer()
+	if err != nil {
 		return err
 	}
-	if pub, err := nostr.GetPublicKey(sk); err == nil {
+	if pub, err := signer.GetPublicKey(context.TODO()); err == nil {
 		if _, err := nip19.EncodePublicKey(pub); err != nil {
 			return err
 		}
@@ -34,39 +33,41 @@ func DoRepost(cCtx *cli.Context) error {
 		return err
 	}
 
+	var hint string
 	if evp := sdk.InputToEventPointer(id); evp != nil {
 		id = evp.ID
+		if len(evp.Relays) > 0 {
+			hint = evp.Relays[0]
+		}
 	} else {
 		return fmt.Errorf("failed to parse event from '%s'", id)
 	}
-	ev.Tags = ev.Tags.AppendUnique(nostr.Tag{"e", id})
-	filter := nostr.Filter{
-		Kinds: []int{nostr.KindTextNote},
-		IDs:   []string{id},
+	evs := cfg.Events(nostr.Filter{IDs: []string{id}})
+	if len(evs) == 0 {
+		return fmt.Errorf("failed to get event '%s'", id)
 	}
+	target := evs[0]
 
+	ev.Tags = ev.Tags.AppendUnique(nostr.Tag{"e", target.ID, hint})
+	ev.Tags = ev.Tags.AppendUnique(nostr.Tag{"p", target.PubKey})
+	if target.Kind == nostr.KindTextNote {
+		ev.Kind = nostr.KindRepost
+	} else {
+		// NIP-18 generic repost
+		ev.Kind = domain.KindGenericRepost
+		ev.Tags = ev.Tags.AppendUnique(nostr.Tag{"k", strconv.Itoa(target.Kind)})
+		if 30000 <= target.Kind && target.Kind < 40000 {
+			ev.Tags = ev.Tags.AppendUnique(nostr.Tag{"a", fmt.Sprintf("%d:%s:%s", target.Kind, target.PubKey, target.Tags.GetD()), hint})
+		}
+	}
 	ev.CreatedAt = nostr.Now()
-	ev.Kind = nostr.KindRepost
-	ev.Content = ""
-
-	var first atomic.Bool
-	first.Store(true)
+	ev.Content = target.String()
+	if err := signer.SignEvent(context.TODO(), &ev); err != nil {
+		return err
+	}
 
 	var success atomic.Int64
 	cfg.Do(domain.Relay{Write: true}, func(ctx context.Context, relay *nostr.Relay) bool {
-		if first.Load() {
-			evs, err := relay.QuerySync(ctx, filter)
-			if err != nil {
-				return true
-			}
-			for _, tmp := range evs {
-				ev.Tags = ev.Tags.AppendUnique(nostr.Tag{"p", tmp.ID})
-			}
-			first.Store(false)
-			if err := ev.Sign(sk); err != nil {
-				return true
-			}
-		}
 		err := relay.Publish(ctx, ev)
 		if err != nil {
 			fmt.Fprintln(os.Stderr, relay.URL, err)
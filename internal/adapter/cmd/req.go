@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/mattn/algia/internal/domain"
+	"os"
+	"os/signal"
+	"sync"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+func DoReq(cCtx *cli.Context) error {
+	stream := cCtx.Bool("stream")
+
+	cfg := cCtx.App.Metadata["config"].(*domain.Config)
+
+	filter := nostr.Filter{
+		Kinds:  cCtx.IntSlice("kind"),
+		Limit:  cCtx.Int("limit"),
+		Search: cCtx.String("search"),
+		Tags:   nostr.TagMap{},
+	}
+	for _, s := range cCtx.StringSlice("id") {
+		id, err := hexID(s)
+		if err != nil {
+			return err
+		}
+		filter.IDs = append(filter.IDs, id)
+	}
+	for _, s := range cCtx.StringSlice("author") {
+		pub, err := hexPubKey(s)
+		if err != nil {
+			return err
+		}
+		filter.Authors = append(filter.Authors, pub)
+	}
+	for _, s := range cCtx.StringSlice("e") {
+		id, err := hexID(s)
+		if err != nil {
+			return err
+		}
+		filter.Tags["e"] = append(filter.Tags["e"], id)
+	}
+	for _, s := range cCtx.StringSlice("p") {
+		pub, err := hexPubKey(s)
+		if err != nil {
+			return err
+		}
+		filter.Tags["p"] = append(filter.Tags["p"], pub)
+	}
+	for _, s := range cCtx.StringSlice("t") {
+		filter.Tags["t"] = append(filter.Tags["t"], s)
+	}
+	for _, s := range cCtx.StringSlice("tag") {
+		tag, err := parseTag(s)
+		if err != nil {
+			return err
+		}
+		filter.Tags[tag[0]] = append(filter.Tags[tag[0]], tag[1:]...)
+	}
+	if len(filter.Tags) == 0 {
+		filter.Tags = nil
+	}
+	if err := setTimeRange(cCtx, &filter); err != nil {
+		return err
+	}
+
+	urls := cCtx.StringSlice("relay")
+	if len(urls) == 0 {
+		for k, v := range cfg.Relays {
+			if (filter.Search != "" && v.Search) || (filter.Search == "" && v.Read) {
+				urls = append(urls, k)
+			}
+		}
+	}
+	if len(urls) == 0 {
+		return fmt.Errorf("no relays to query")
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	if stream {
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+		pool := nostr.NewSimplePool(ctx)
+		for ie := range pool.SubMany(ctx, urls, nostr.Filters{filter}) {
+			enc.Encode(ie.Event)
+		}
+		return nil
+	}
+
+	var mu sync.Mutex
+	seen := map[string]struct{}{}
+	cfg.DoURLs(urls, func(ctx context.Context, relay *nostr.Relay) bool {
+		evs, err := relay.QuerySync(ctx, filter)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, relay.URL, err)
+			return true
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, ev := range evs {
+			if _, ok := seen[ev.ID]; ok {
+				continue
+			}
+			seen[ev.ID] = struct{}{}
+			enc.Encode(ev)
+		}
+		return true
+	})
+	return nil
+}
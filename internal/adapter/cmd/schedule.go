@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mattn/algia/internal/domain"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+)
+
+// parseScheduleTime parses unix time, RFC3339, local date and time (e.g.
+// 2026-11-01 09:00) or duration after now (e.g. 2h). The time must be in the
+// future.
+func parseScheduleTime(s string) (nostr.Timestamp, error) {
+	var t time.Time
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		t = time.Unix(n, 0)
+	} else if d, err := time.ParseDuration(s); err == nil {
+		t = time.Now().Add(d)
+	} else {
+		for _, layout := range []string{time.RFC3339, time.DateTime, "2006-01-02 15:04", time.DateOnly} {
+			if t, err = time.ParseInLocation(layout, s, time.Local); err == nil {
+				break
+			}
+		}
+		if t.IsZero() {
+			return 0, fmt.Errorf("invalid time '%s'", s)
+		}
+	}
+	if !t.After(time.Now()) {
+		return 0, fmt.Errorf("time '%s' is in the past", s)
+	}
+	return nostr.Timestamp(t.Unix()), nil
+}
+
+// scheduleNote signs the note with the time and puts it into the queue.
+func scheduleNote(cfg *domain.Config, ev *nostr.Event, at nostr.Timestamp) error {
+	if err := signNote(cfg, ev, at); err != nil {
+		return err
+	}
+	if err := cfg.SaveSchedule(&domain.Scheduled{Event: ev}); err != nil {
+		return err
+	}
+	note, err := nip19.EncodeNote(ev.ID)
+	if err != nil {
+		return err
+	}
+	fmt.Println(note)
+	return nil
+}
+
+// flushSchedules publishes the events which are due. Failed ones are kept in
+// the queue to retry later.
+func flushSchedules(cfg *domain.Config) error {
+	schedules, err := cfg.Schedules()
+	if err != nil {
+		return err
+	}
+	now := nostr.Now()
+	failed := 0
+	for _, s := range schedules {
+		if !s.Due(now) {
+			continue
+		}
+		note, _ := nip19.EncodeNote(s.Event.ID)
+		if err := publishEvent(cfg, s.Event); err != nil {
+			fmt.Fprintln(os.Stderr, note, err)
+			s.Failed(err)
+			if s.GaveUp() {
+				fmt.Fprintf(os.Stderr, "%s: gave up after %d attempts\n", note, s.Attempts)
+			}
+			if err := cfg.SaveSchedule(s); err != nil {
+				return err
+			}
+			failed++
+			continue
+		}
+		// it may be canceled while publishing
+		cfg.RemoveSchedule(s.Event.ID)
+		if cfg.Verbose {
+			fmt.Println(note)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("cannot post %d scheduled events", failed)
+	}
+	return nil
+}
+
+func DoScheduleFlush(cCtx *cli.Context) error {
+	cfg := cCtx.App.Metadata["config"].(*domain.Config)
+
+	return flushSchedules(cfg)
+}
+
+func DoScheduleRun(cCtx *cli.Context) error {
+	interval := cCtx.Duration("interval")
+	if interval <= 0 {
+		return errors.New("interval must be positive")
+	}
+
+	cfg := cCtx.App.Metadata["config"].(*domain.Config)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := flushSchedules(cfg); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func DoScheduleList(cCtx *cli.Context) error {
+	j := cCtx.Bool("json")
+
+	cfg := cCtx.App.Metadata["config"].(*domain.Config)
+
+	schedules, err := cfg.Schedules()
+	if err != nil {
+		return err
+	}
+	if j {
+		enc := json.NewEncoder(os.Stdout)
+		for _, s := range schedules {
+			enc.Encode(s)
+		}
+		return nil
+	}
+	for _, s := range schedules {
+		note, err := nip19.EncodeNote(s.Event.ID)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s %s kind:%d %s\n",
+			note,
+			s.Event.CreatedAt.Time().Format(time.DateTime),
+			s.Event.Kind,
+			summary(s.Event.Content))
+		if s.GaveUp() {
+			fmt.Printf("    gave up after %d attempts: %s\n", s.Attempts, s.Error)
+		} else if s.Error != "" {
+			fmt.Printf("    failed %d times, next try at %s: %s\n",
+				s.Attempts,
+				s.NextTry.Time().Format(time.DateTime),
+				s.Error)
+		}
+	}
+	return nil
+}
+
+func DoScheduleCancel(cCtx *cli.Context) error {
+	if cCtx.Args().Len() == 0 {
+		return cli.ShowSubcommandHelp(cCtx)
+	}
+
+	cfg := cCtx.App.Metadata["config"].(*domain.Config)
+
+	for _, arg := range cCtx.Args().Slice() {
+		id, err := hexID(arg)
+		if err != nil {
+			return err
+		}
+		if err := cfg.RemoveSchedule(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -11,6 +11,8 @@ func DoSearch(cCtx *cli.Context) error {
 	n := cCtx.Int("n")
 	j := cCtx.Bool("json")
 	extra := cCtx.Bool("extra")
+	withStats := cCtx.Bool("stats")
+	page := cCtx.Bool("page")
 
 	cfg := cCtx.App.Metadata["config"].(*domain.Config)
 
@@ -33,7 +35,21 @@ func DoSearch(cCtx *cli.Context) error {
 		Limit:  n,
 	}
 
-	evs := cfg.Events(filter)
-	cfg.PrintEvents(evs, followsMap, j, extra)
+	if err := setTimeRange(cCtx, &filter); err != nil {
+		return err
+	}
+
+	evs, err := collectPage(cfg, filter, n, cCtx.String("cursor"))
+	if err != nil {
+		return err
+	}
+	var stats map[string]*domain.Stats
+	if withStats {
+		stats = cfg.EventStats(evs)
+	}
+	cfg.PrintEvents(evs, followsMap, stats, j, extra)
+	if page {
+		printCursor(cCtx, evs)
+	}
 	return nil
 }
@@ -7,8 +7,7 @@ import (
 	"fmt"
 	"github.com/mattn/algia/internal/domain"
 	"github.com/nbd-wtf/go-nostr"
-	"github.com/nbd-wtf/go-nostr/nip19"
-	"github.com/nbd-wtf/nostr-sdk"
+	"github.com/nbd-wtf/go-nostr/sdk"
 	"github.com/urfave/cli/v2"
 	"os"
 	"regexp"
@@ -38,13 +37,11 @@ func DoStream(cCtx *cli.Context) error {
 	}
 	defer relay.Close()
 
-	var sk string
-	if _, s, err := nip19.Decode(cfg.PrivateKey); err == nil {
-		sk = s.(string)
-	} else {
+	signer, err := cfg.Signer()
+	if err != nil {
 		return err
 	}
-	pub, err := nostr.GetPublicKey(sk)
+	pub, err := signer.GetPublicKey(context.TODO())
 	if err != nil {
 		return err
 	}
@@ -95,7 +92,7 @@ func DoStream(cCtx *cli.Context) error {
 			evr.Tags = evr.Tags.AppendUnique(nostr.Tag{"e", ev.ID, "", "reply"})
 			evr.CreatedAt = nostr.Now()
 			evr.Kind = nostr.KindTextNote
-			if err := evr.Sign(sk); err != nil {
+			if err := signer.SignEvent(context.TODO(), &evr); err != nil {
 				return err
 			}
 			cfg.Do(domain.Relay{Write: true}, func(ctx context.Context, relay *nostr.Relay) bool {
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/mattn/algia/internal/domain"
+	"sort"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip10"
+	"github.com/nbd-wtf/go-nostr/sdk"
+)
+
+func DoThread(cCtx *cli.Context) error {
+	id := cCtx.String("id")
+	j := cCtx.Bool("json")
+	extra := cCtx.Bool("extra")
+	withStats := cCtx.Bool("stats")
+
+	cfg := cCtx.App.Metadata["config"].(*domain.Config)
+
+	if evp := sdk.InputToEventPointer(id); evp != nil {
+		id = evp.ID
+	} else {
+		return fmt.Errorf("failed to parse event from '%s'", id)
+	}
+
+	// get followers
+	followsMap, err := cfg.GetFollows(cCtx.String("a"))
+	if err != nil {
+		return err
+	}
+
+	evs := cfg.Events(nostr.Filter{IDs: []string{id}})
+	if len(evs) == 0 {
+		return fmt.Errorf("failed to get event '%s'", id)
+	}
+
+	// walk up to the root
+	root := evs[0]
+	seen := map[string]struct{}{root.ID: {}}
+	for {
+		var parent *nostr.Event
+		for _, tag := range []nostr.Tag{threadRoot(root.Tags), immediateReply(root.Tags)} {
+			if tag == nil {
+				continue
+			}
+			if _, ok := seen[tag[1]]; ok {
+				continue
+			}
+			if evs := cfg.Events(nostr.Filter{IDs: []string{tag[1]}}); len(evs) > 0 {
+				parent = evs[0]
+				break
+			}
+		}
+		if parent == nil {
+			break
+		}
+		seen[parent.ID] = struct{}{}
+		root = parent
+	}
+
+	replies := threadReplies(cfg, root.ID)
+	events := map[string]*nostr.Event{root.ID: root, evs[0].ID: evs[0]}
+	for _, ev := range replies {
+		events[ev.ID] = ev
+	}
+	children := map[string][]*nostr.Event{}
+	for _, ev := range append([]*nostr.Event{evs[0]}, replies...) {
+		if ev.ID == root.ID {
+			continue
+		}
+		parent := root.ID
+		if tag := immediateReply(ev.Tags); tag != nil {
+			if _, ok := events[tag[1]]; ok && tag[1] != ev.ID {
+				parent = tag[1]
+			}
+		}
+		children[parent] = append(children[parent], ev)
+	}
+
+	var ordered []*nostr.Event
+	var depths []int
+	printed := map[string]struct{}{}
+	var walk func(ev *nostr.Event, depth int)
+	walk = func(ev *nostr.Event, depth int) {
+		if _, ok := printed[ev.ID]; ok {
+			return
+		}
+		printed[ev.ID] = struct{}{}
+		ordered = append(ordered, ev)
+		depths = append(depths, depth)
+		for _, child := range children[ev.ID] {
+			walk(child, depth+1)
+		}
+	}
+	walk(root, 0)
+
+	var stats map[string]*domain.Stats
+	if withStats {
+		stats = cfg.EventStats(ordered)
+	}
+
+	if j {
+		cfg.PrintEvents(ordered, followsMap, stats, j, extra)
+		return nil
+	}
+	for i, ev := range ordered {
+		cfg.PrintEvent(ev, followsMap, strings.Repeat("  ", depths[i]))
+		if s, ok := stats[ev.ID]; ok {
+			cfg.PrintStats(s, strings.Repeat("  ", depths[i]))
+		}
+	}
+	return nil
+}
+
+// threadRepliesBatch is the number of ids in a filter to get replies.
+const threadRepliesBatch = 50
+
+// threadReplies returns the replies under the root, older first. Replies
+// which tag only their parent are found by getting replies of the replies
+// until nothing new comes back.
+func threadReplies(cfg *domain.Config, root string) []*nostr.Event {
+	found := map[string]*nostr.Event{}
+	pending := []string{root}
+	for len(pending) > 0 {
+		batch := pending[:min(len(pending), threadRepliesBatch)]
+		pending = pending[len(batch):]
+		for _, ev := range cfg.Events(nostr.Filter{
+			Kinds: []int{nostr.KindTextNote},
+			Tags:  nostr.TagMap{"e": batch},
+		}) {
+			if _, ok := found[ev.ID]; ok || ev.ID == root {
+				continue
+			}
+			found[ev.ID] = ev
+			pending = append(pending, ev.ID)
+		}
+	}
+	replies := make([]*nostr.Event, 0, len(found))
+	for _, ev := range found {
+		replies = append(replies, ev)
+	}
+	sort.Slice(replies, func(i, j int) bool {
+		return replies[i].CreatedAt < replies[j].CreatedAt
+	})
+	return replies
+}
+
+func immediateReply(tags nostr.Tags) nostr.Tag {
+	if tag := nip10.GetImmediateReply(tags); tag != nil && len(*tag) >= 2 {
+		return *tag
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/mattn/algia/internal/domain"
+	"github.com/mattn/algia/internal/testrelay"
+	"github.com/nbd-wtf/go-nostr"
+)
+
+func TestThreadReplies(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	r := testrelay.New()
+	defer r.Close()
+
+	ctx := context.Background()
+	relay, err := nostr.RelayConnect(ctx, r.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer relay.Close()
+
+	sk := nostr.GeneratePrivateKey()
+	now := nostr.Now()
+	post := func(content string, tags nostr.Tags) *nostr.Event {
+		ev := nostr.Event{Kind: nostr.KindTextNote, CreatedAt: now, Tags: tags, Content: content}
+		if err := ev.Sign(sk); err != nil {
+			t.Fatal(err)
+		}
+		if err := relay.Publish(ctx, ev); err != nil {
+			t.Fatal(err)
+		}
+		return &ev
+	}
+
+	root := post("root", nostr.Tags{})
+	post("other", nostr.Tags{})
+	want := map[string]bool{}
+	// more branches than a batch, each reply tags only its parent
+	for i := 0; i < threadRepliesBatch+10; i++ {
+		a := post(fmt.Sprint("a", i), nostr.Tags{{"e", root.ID, "", "root"}})
+		b := post(fmt.Sprint("b", i), nostr.Tags{{"e", a.ID, "", "reply"}})
+		c := post(fmt.Sprint("c", i), nostr.Tags{{"e", b.ID}})
+		want[a.ID], want[b.ID], want[c.ID] = true, true, true
+	}
+
+	cfg := &domain.Config{Relays: map[string]domain.Relay{r.URL: {Read: true, Write: true}}}
+	defer cfg.Close()
+	replies := threadReplies(cfg, root.ID)
+	if len(replies) != len(want) {
+		t.Fatalf("want %d replies, but got %d", len(want), len(replies))
+	}
+	for _, ev := range replies {
+		if !want[ev.ID] {
+			t.Fatalf("want only replies in the thread, but got %q", ev.Content)
+		}
+	}
+}
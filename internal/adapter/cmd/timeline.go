@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"github.com/mattn/algia/internal/domain"
 	"github.com/nbd-wtf/go-nostr"
-	"github.com/nbd-wtf/nostr-sdk"
+	"github.com/nbd-wtf/go-nostr/sdk"
 	"github.com/urfave/cli/v2"
 )
 
@@ -14,6 +14,8 @@ func DoTimeline(cCtx *cli.Context) error {
 	n := cCtx.Int("n")
 	j := cCtx.Bool("json")
 	extra := cCtx.Bool("extra")
+	withStats := cCtx.Bool("stats")
+	page := cCtx.Bool("page")
 	article := cCtx.Bool("article")
 
 	cfg := cCtx.App.Metadata["config"].(*domain.Config)
@@ -48,7 +50,21 @@ func DoTimeline(cCtx *cli.Context) error {
 		Limit:   n,
 	}
 
-	evs := cfg.Events(filter)
-	cfg.PrintEvents(evs, followsMap, j, extra)
+	if err := setTimeRange(cCtx, &filter); err != nil {
+		return err
+	}
+
+	evs, err := collectPage(cfg, filter, n, cCtx.String("cursor"))
+	if err != nil {
+		return err
+	}
+	var stats map[string]*domain.Stats
+	if withStats {
+		stats = cfg.EventStats(evs)
+	}
+	cfg.PrintEvents(evs, followsMap, stats, j, extra)
+	if page {
+		printCursor(cCtx, evs)
+	}
 	return nil
 }
@@ -6,8 +6,7 @@ import (
 	"fmt"
 	"github.com/mattn/algia/internal/domain"
 	"github.com/nbd-wtf/go-nostr"
-	"github.com/nbd-wtf/go-nostr/nip19"
-	"github.com/nbd-wtf/nostr-sdk"
+	"github.com/nbd-wtf/go-nostr/sdk"
 	"github.com/urfave/cli/v2"
 	"os"
 	"sync"
@@ -24,13 +23,11 @@ func DoUnlike(cCtx *cli.Context) error {
 
 	cfg := cCtx.App.Metadata["config"].(*domain.Config)
 
-	var sk string
-	if _, s, err := nip19.Decode(cfg.PrivateKey); err == nil {
-		sk = s.(string)
-	} else {
+	signer, err := cfg.Signer()
+	if err != nil {
 		return err
 	}
-	pub, err := nostr.GetPublicKey(sk)
+	pub, err := signer.GetPublicKey(context.TODO())
 	if err != nil {
 		return err
 	}
@@ -58,7 +55,7 @@ func DoUnlike(cCtx *cli.Context) error {
 	ev.Tags = ev.Tags.AppendUnique(nostr.Tag{"e", likeID})
 	ev.CreatedAt = nostr.Now()
 	ev.Kind = nostr.KindDeletion
-	if err := ev.Sign(sk); err != nil {
+	if err := signer.SignEvent(context.TODO(), &ev); err != nil {
 		return err
 	}
 
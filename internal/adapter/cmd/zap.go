@@ -111,10 +111,8 @@ func DoZap(cCtx *cli.Context) error {
 
 	cfg := cCtx.App.Metadata["config"].(*domain.Config)
 
-	var sk string
-	if _, s, err := nip19.Decode(cfg.PrivateKey); err == nil {
-		sk = s.(string)
-	} else {
+	signer, err := cfg.Signer()
+	if err != nil {
 		return err
 	}
 
@@ -122,7 +120,7 @@ func DoZap(cCtx *cli.Context) error {
 	zr := nostr.Event{}
 	zr.Tags = nostr.Tags{}
 
-	if pub, err := nostr.GetPublicKey(sk); err == nil {
+	if pub, err := signer.GetPublicKey(context.TODO()); err == nil {
 		if _, err := nip19.EncodePublicKey(pub); err != nil {
 			return err
 		}
@@ -163,7 +161,7 @@ func DoZap(cCtx *cli.Context) error {
 	zr.Kind = nostr.KindZapRequest // 9734
 	zr.CreatedAt = nostr.Now()
 	zr.Content = comment
-	if err := zr.Sign(sk); err != nil {
+	if err := signer.SignEvent(context.TODO(), &zr); err != nil {
 		return err
 	}
 	b, err := zr.MarshalJSON()
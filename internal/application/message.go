@@ -15,14 +15,12 @@ import (
 func PostMsg(cCtx *cli.Context, msg string) error {
 	cfg := cCtx.App.Metadata["config"].(*domain.Config)
 
-	var sk string
-	if _, s, err := nip19.Decode(cfg.PrivateKey); err == nil {
-		sk = s.(string)
-	} else {
+	signer, err := cfg.Signer()
+	if err != nil {
 		return err
 	}
 	ev := nostr.Event{}
-	if pub, err := nostr.GetPublicKey(sk); err == nil {
+	if pub, err := signer.GetPublicKey(context.TODO()); err == nil {
 		if _, err := nip19.EncodePublicKey(pub); err != nil {
 			return err
 		}
@@ -35,7 +33,7 @@ func PostMsg(cCtx *cli.Context, msg string) error {
 	ev.CreatedAt = nostr.Now()
 	ev.Kind = nostr.KindTextNote
 	ev.Tags = nostr.Tags{}
-	if err := ev.Sign(sk); err != nil {
+	if err := signer.SignEvent(context.TODO(), &ev); err != nil {
 		return err
 	}
 
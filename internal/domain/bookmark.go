@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+const KindBookmarkList = 10003
+
+// PrivateTags is
+func (cfg *Config) PrivateTags(ev *nostr.Event) (nostr.Tags, error) {
+	tags := nostr.Tags{}
+	if strings.TrimSpace(ev.Content) == "" {
+		return tags, nil
+	}
+
+	signer, err := cfg.Signer()
+	if err != nil {
+		return nil, err
+	}
+	pub, err := signer.GetPublicKey(context.TODO())
+	if err != nil {
+		return nil, err
+	}
+
+	var content string
+	if strings.Contains(ev.Content, "?iv=") {
+		// older lists are encrypted with NIP-04
+		content, err = signer.Nip04Decrypt(context.TODO(), pub, ev.Content)
+	} else {
+		content, err = signer.Nip44Decrypt(context.TODO(), pub, ev.Content)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(content), &tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// EncryptTags is
+func (cfg *Config) EncryptTags(tags nostr.Tags) (string, error) {
+	if len(tags) == 0 {
+		return "", nil
+	}
+
+	signer, err := cfg.Signer()
+	if err != nil {
+		return "", err
+	}
+	pub, err := signer.GetPublicKey(context.TODO())
+	if err != nil {
+		return "", err
+	}
+
+	b, err := json.Marshal(tags)
+	if err != nil {
+		return "", err
+	}
+	return signer.Nip44Encrypt(context.TODO(), pub, string(b))
+}
@@ -0,0 +1,261 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip04"
+	"github.com/nbd-wtf/go-nostr/nip44"
+	"github.com/nbd-wtf/go-nostr/nip46"
+)
+
+// BunkerSigner is a Signer which asks a NIP-46 remote signer over relays.
+type BunkerSigner struct {
+	Timeout time.Duration
+
+	sk      string
+	target  string
+	relays  []*nostr.Relay
+	ss      []byte
+	ck      [32]byte
+	prefix  string
+	serial  atomic.Uint64
+	mu      sync.Mutex
+	waiters map[string]chan nip46.Response
+	pub     string
+	pubMu   sync.Mutex
+}
+
+// ConnectBunker connects to the remote signer given as bunker://<pubkey>?relay=...&secret=...
+// using clientKey to sign and encrypt the requests.
+func ConnectBunker(ctx context.Context, clientKey string, uri string) (*BunkerSigner, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "bunker" {
+		return nil, fmt.Errorf("wrong scheme '%s', must be bunker://", u.Scheme)
+	}
+	if !nostr.IsValidPublicKey(u.Host) {
+		return nil, fmt.Errorf("'%s' is not a valid public key", u.Host)
+	}
+	if len(u.Query()["relay"]) == 0 {
+		return nil, errors.New("bunker URI has no relay")
+	}
+
+	bs := &BunkerSigner{
+		Timeout: 2 * time.Minute,
+		sk:      clientKey,
+		target:  u.Host,
+		prefix:  "algia-" + strconv.Itoa(rand.Intn(65536)),
+		waiters: map[string]chan nip46.Response{},
+	}
+	if bs.ss, err = nip04.ComputeSharedSecret(bs.target, bs.sk); err != nil {
+		return nil, err
+	}
+	if bs.ck, err = nip44.GenerateConversationKey(bs.target, bs.sk); err != nil {
+		return nil, err
+	}
+	pub, err := nostr.GetPublicKey(bs.sk)
+	if err != nil {
+		return nil, err
+	}
+
+	// subscribe before any request is sent so no response is missed
+	since := nostr.Now() - 60
+	filter := nostr.Filter{
+		Kinds: []int{nostr.KindNostrConnect},
+		Tags:  nostr.TagMap{"p": []string{pub}},
+		Since: &since,
+	}
+	for _, r := range u.Query()["relay"] {
+		relay, err := nostr.RelayConnect(ctx, r)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, r, err)
+			continue
+		}
+		sub, err := relay.Subscribe(context.Background(), nostr.Filters{filter})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, r, err)
+			relay.Close()
+			continue
+		}
+		go bs.listen(sub)
+		bs.relays = append(bs.relays, relay)
+	}
+	if len(bs.relays) == 0 {
+		return nil, errors.New("cannot connect bunker relays")
+	}
+
+	if _, err := bs.RPC(ctx, "connect", []string{bs.target, u.Query().Get("secret")}); err != nil {
+		bs.Close()
+		return nil, err
+	}
+	return bs, nil
+}
+
+func (bs *BunkerSigner) listen(sub *nostr.Subscription) {
+	for ev := range sub.Events {
+		if ev.PubKey != bs.target {
+			continue
+		}
+		content, err := nip44.Decrypt(ev.Content, bs.ck)
+		if err != nil {
+			if content, err = nip04.Decrypt(ev.Content, bs.ss); err != nil {
+				continue
+			}
+		}
+		var resp nip46.Response
+		if err := json.Unmarshal([]byte(content), &resp); err != nil {
+			continue
+		}
+		if resp.Result == "auth_url" {
+			fmt.Fprintf(os.Stderr, "open %s to authorize the request\n", resp.Error)
+			continue
+		}
+		bs.mu.Lock()
+		if ch, ok := bs.waiters[resp.ID]; ok {
+			delete(bs.waiters, resp.ID)
+			ch <- resp
+		}
+		bs.mu.Unlock()
+	}
+}
+
+// RPC sends the request to the remote signer and waits for the response.
+func (bs *BunkerSigner) RPC(ctx context.Context, method string, params []string) (string, error) {
+	id := bs.prefix + "-" + strconv.FormatUint(bs.serial.Add(1), 10)
+	b, err := json.Marshal(nip46.Request{ID: id, Method: method, Params: params})
+	if err != nil {
+		return "", err
+	}
+	content, err := nip04.Encrypt(string(b), bs.ss)
+	if err != nil {
+		return "", err
+	}
+	ev := nostr.Event{
+		Kind:      nostr.KindNostrConnect,
+		CreatedAt: nostr.Now(),
+		Tags:      nostr.Tags{nostr.Tag{"p", bs.target}},
+		Content:   content,
+	}
+	if err := ev.Sign(bs.sk); err != nil {
+		return "", err
+	}
+
+	ch := make(chan nip46.Response, 1)
+	bs.mu.Lock()
+	bs.waiters[id] = ch
+	bs.mu.Unlock()
+	defer func() {
+		bs.mu.Lock()
+		delete(bs.waiters, id)
+		bs.mu.Unlock()
+	}()
+
+	published := 0
+	for _, relay := range bs.relays {
+		if err := relay.Publish(ctx, ev); err != nil {
+			fmt.Fprintln(os.Stderr, relay.URL, err)
+			continue
+		}
+		published++
+	}
+	if published == 0 {
+		return "", errors.New("cannot send request to bunker")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, bs.Timeout)
+	defer cancel()
+	select {
+	case resp := <-ch:
+		if resp.Error != "" {
+			return "", fmt.Errorf("bunker: %s", resp.Error)
+		}
+		return resp.Result, nil
+	case <-ctx.Done():
+		return "", fmt.Errorf("bunker did not respond to %s: %w", method, ctx.Err())
+	}
+}
+
+// GetPublicKey is
+func (bs *BunkerSigner) GetPublicKey(ctx context.Context) (string, error) {
+	bs.pubMu.Lock()
+	defer bs.pubMu.Unlock()
+
+	if bs.pub != "" {
+		return bs.pub, nil
+	}
+	pub, err := bs.RPC(ctx, "get_public_key", []string{})
+	if err != nil {
+		return "", err
+	}
+	if !nostr.IsValidPublicKey(pub) {
+		return "", fmt.Errorf("bunker returned invalid public key '%s'", pub)
+	}
+	bs.pub = pub
+	return bs.pub, nil
+}
+
+// SignEvent is
+func (bs *BunkerSigner) SignEvent(ctx context.Context, ev *nostr.Event) error {
+	pub, err := bs.GetPublicKey(ctx)
+	if err != nil {
+		return err
+	}
+	ev.PubKey = pub
+	result, err := bs.RPC(ctx, "sign_event", []string{ev.String()})
+	if err != nil {
+		return err
+	}
+	var signed nostr.Event
+	if err := json.Unmarshal([]byte(result), &signed); err != nil {
+		return err
+	}
+	if signed.PubKey != pub || signed.GetID() != ev.GetID() {
+		return errors.New("bunker signed a different event")
+	}
+	if ok, err := signed.CheckSignature(); err != nil || !ok {
+		return errors.New("bunker returned invalid signature")
+	}
+	ev.ID = signed.ID
+	ev.Sig = signed.Sig
+	return nil
+}
+
+// Nip04Encrypt is
+func (bs *BunkerSigner) Nip04Encrypt(ctx context.Context, pub string, plaintext string) (string, error) {
+	return bs.RPC(ctx, "nip04_encrypt", []string{pub, plaintext})
+}
+
+// Nip04Decrypt is
+func (bs *BunkerSigner) Nip04Decrypt(ctx context.Context, pub string, ciphertext string) (string, error) {
+	return bs.RPC(ctx, "nip04_decrypt", []string{pub, ciphertext})
+}
+
+// Nip44Encrypt is
+func (bs *BunkerSigner) Nip44Encrypt(ctx context.Context, pub string, plaintext string) (string, error) {
+	return bs.RPC(ctx, "nip44_encrypt", []string{pub, plaintext})
+}
+
+// Nip44Decrypt is
+func (bs *BunkerSigner) Nip44Decrypt(ctx context.Context, pub string, ciphertext string) (string, error) {
+	return bs.RPC(ctx, "nip44_decrypt", []string{pub, ciphertext})
+}
+
+// Close is
+func (bs *BunkerSigner) Close() {
+	for _, relay := range bs.relays {
+		relay.Close()
+	}
+}
@@ -0,0 +1,279 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mattn/algia/internal/testrelay"
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip04"
+	"github.com/nbd-wtf/go-nostr/nip46"
+)
+
+func startTestRelay(t *testing.T) string {
+	r := testrelay.New()
+	t.Cleanup(r.Close)
+	return r.URL
+}
+
+// startTestBunker runs a remote signer for userKey which listens with
+// bunkerKey, and returns the bunker URI.
+func startTestBunker(t *testing.T, url, bunkerKey, userKey, secret string, connects *atomic.Int32) string {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	relay, err := nostr.RelayConnect(ctx, url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { relay.Close() })
+	bunkerPub, _ := nostr.GetPublicKey(bunkerKey)
+	sub, err := relay.Subscribe(ctx, nostr.Filters{{
+		Kinds: []int{nostr.KindNostrConnect},
+		Tags:  nostr.TagMap{"p": []string{bunkerPub}},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := NewKeySigner(userKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for ev := range sub.Events {
+			ss, err := nip04.ComputeSharedSecret(ev.PubKey, bunkerKey)
+			if err != nil {
+				continue
+			}
+			plain, err := nip04.Decrypt(ev.Content, ss)
+			if err != nil {
+				continue
+			}
+			var req nip46.Request
+			if err := json.Unmarshal([]byte(plain), &req); err != nil {
+				continue
+			}
+			resp := nip46.Response{ID: req.ID}
+			switch req.Method {
+			case "connect":
+				if req.Params[1] != secret {
+					resp.Error = "invalid secret"
+				} else {
+					connects.Add(1)
+					resp.Result = "ack"
+				}
+			case "get_public_key":
+				resp.Result = user.pub
+			case "sign_event":
+				var target nostr.Event
+				if err = json.Unmarshal([]byte(req.Params[0]), &target); err == nil {
+					err = user.SignEvent(ctx, &target)
+				}
+				resp.Result = target.String()
+			case "nip44_encrypt":
+				resp.Result, err = user.Nip44Encrypt(ctx, req.Params[0], req.Params[1])
+			case "nip44_decrypt":
+				resp.Result, err = user.Nip44Decrypt(ctx, req.Params[0], req.Params[1])
+			default:
+				resp.Error = "unsupported method " + req.Method
+			}
+			if err != nil {
+				resp.Error = err.Error()
+			}
+
+			b, _ := json.Marshal(resp)
+			content, err := nip04.Encrypt(string(b), ss)
+			if err != nil {
+				continue
+			}
+			reply := nostr.Event{
+				Kind:      nostr.KindNostrConnect,
+				CreatedAt: nostr.Now(),
+				Tags:      nostr.Tags{{"p", ev.PubKey}},
+				Content:   content,
+			}
+			reply.Sign(bunkerKey)
+			relay.Publish(ctx, reply)
+		}
+	}()
+	return "bunker://" + bunkerPub + "?relay=" + url + "&secret=" + secret
+}
+
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestBunkerSigner(t *testing.T) {
+	url := startTestRelay(t)
+	userKey := nostr.GeneratePrivateKey()
+	userPub, _ := nostr.GetPublicKey(userKey)
+	var connects atomic.Int32
+	uri := startTestBunker(t, url, nostr.GeneratePrivateKey(), userKey, "s3cret", &connects)
+	ctx := testContext(t)
+
+	bs, err := ConnectBunker(ctx, nostr.GeneratePrivateKey(), uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bs.Close()
+	if got := connects.Load(); got != 1 {
+		t.Fatalf("want 1 connect, but got %d", got)
+	}
+
+	pub, err := bs.GetPublicKey(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pub != userPub {
+		t.Fatalf("want public key %s, but got %s", userPub, pub)
+	}
+
+	ev := nostr.Event{Kind: nostr.KindTextNote, CreatedAt: nostr.Now(), Tags: nostr.Tags{}, Content: "hello"}
+	if err := bs.SignEvent(ctx, &ev); err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := ev.CheckSignature(); err != nil || !ok {
+		t.Fatalf("invalid signature: %v", err)
+	}
+	if ev.PubKey != userPub {
+		t.Fatalf("want signed by %s, but got %s", userPub, ev.PubKey)
+	}
+
+	peer, err := NewKeySigner(nostr.GeneratePrivateKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := bs.Nip44Encrypt(ctx, peer.pub, "to peer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain, err := peer.Nip44Decrypt(ctx, userPub, ciphertext); err != nil || plain != "to peer" {
+		t.Fatalf("want %q, but got %q: %v", "to peer", plain, err)
+	}
+	ciphertext, err = peer.Nip44Encrypt(ctx, userPub, "from peer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain, err := bs.Nip44Decrypt(ctx, peer.pub, ciphertext); err != nil || plain != "from peer" {
+		t.Fatalf("want %q, but got %q: %v", "from peer", plain, err)
+	}
+}
+
+func TestBunkerSignerWrongSecret(t *testing.T) {
+	url := startTestRelay(t)
+	var connects atomic.Int32
+	uri := startTestBunker(t, url, nostr.GeneratePrivateKey(), nostr.GeneratePrivateKey(), "s3cret", &connects)
+	uri = strings.Replace(uri, "s3cret", "wrong", 1)
+
+	if _, err := ConnectBunker(testContext(t), nostr.GeneratePrivateKey(), uri); err == nil {
+		t.Fatal("want error for wrong secret")
+	}
+}
+
+func TestConfigSignerConcurrent(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+	cfgDir, err := ConfigDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(cfgDir, "algia"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	url := startTestRelay(t)
+	userKey := nostr.GeneratePrivateKey()
+	userPub, _ := nostr.GetPublicKey(userKey)
+	var connects atomic.Int32
+	uri := startTestBunker(t, url, nostr.GeneratePrivateKey(), userKey, "s3cret", &connects)
+
+	cfg := &Config{Bunker: uri}
+	defer cfg.Close()
+
+	// relay goroutines ask the signer at once
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pub, err := cfg.PublicKey()
+			if err == nil && pub != userPub {
+				err = fmt.Errorf("want public key %s, but got %s", userPub, pub)
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := connects.Load(); got != 1 {
+		t.Fatalf("want 1 connect, but got %d", got)
+	}
+	if cfg.BunkerClientKey == "" {
+		t.Fatal("client key is not saved")
+	}
+}
+
+func TestConfigSignerTempRelay(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+	cfgDir, err := ConfigDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(cfgDir, "algia"), 0700); err != nil {
+		t.Fatal(err)
+	}
+
+	url := startTestRelay(t)
+	var connects atomic.Int32
+	uri := startTestBunker(t, url, nostr.GeneratePrivateKey(), nostr.GeneratePrivateKey(), "s3cret", &connects)
+	saved := &Config{
+		Relays: map[string]Relay{"wss://relay.example.com": {Read: true, Write: true}},
+		Bunker: uri,
+	}
+	if err := saved.Save(""); err != nil {
+		t.Fatal(err)
+	}
+
+	// as --relays does
+	cfg, err := LoadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cfg.Close()
+	cfg.Relays = map[string]Relay{url: {Read: true, Write: true}}
+	cfg.TempRelay = true
+	if _, err := cfg.PublicKey(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.BunkerClientKey == "" || loaded.BunkerClientKey != cfg.BunkerClientKey {
+		t.Fatalf("want client key %q saved, but got %q", cfg.BunkerClientKey, loaded.BunkerClientKey)
+	}
+	if _, ok := loaded.Relays["wss://relay.example.com"]; !ok || len(loaded.Relays) != 1 {
+		t.Fatalf("want relays kept, but got %v", loaded.Relays)
+	}
+}
@@ -0,0 +1,149 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	badgerdb "github.com/dgraph-io/badger/v4"
+	"github.com/fiatjaf/eventstore/badger"
+	"github.com/nbd-wtf/go-nostr"
+)
+
+const (
+	cacheMaxLimit = 10000
+
+	// cacheMaxAge is how long events are kept in the cache. The latest
+	// versions of replaceable events are kept regardless.
+	cacheMaxAge = 30 * 24 * time.Hour
+)
+
+// CacheDir is
+func CacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "algia", "events"), nil
+}
+
+func (cfg *Config) cache() *badger.BadgerBackend {
+	cfg.cacheOnce.Do(func() {
+		dir, err := CacheDir()
+		if err != nil {
+			return
+		}
+		os.MkdirAll(dir, 0700)
+
+		db := &badger.BadgerBackend{
+			Path:     dir,
+			MaxLimit: cacheMaxLimit,
+			BadgerOptionsModifier: func(opts badgerdb.Options) badgerdb.Options {
+				return opts.WithLogger(nil)
+			},
+		}
+		if err := db.Init(); err != nil {
+			// another algia process such as stream may hold the lock
+			if cfg.Verbose {
+				fmt.Fprintln(os.Stderr, "warning: running without cache:", err)
+			}
+			return
+		}
+		pruneCache(db, nostr.Timestamp(time.Now().Add(-cacheMaxAge).Unix()))
+		cfg.db = db
+	})
+	return cfg.db
+}
+
+// pruneCache deletes the events created before until except replaceable ones.
+func pruneCache(db *badger.BadgerBackend, until nostr.Timestamp) {
+	ctx := context.Background()
+	for {
+		ch, err := db.QueryEvents(ctx, nostr.Filter{Until: &until, Limit: cacheMaxLimit})
+		if err != nil {
+			return
+		}
+		var evs []*nostr.Event
+		for ev := range ch {
+			evs = append(evs, ev)
+		}
+		if len(evs) == 0 {
+			return
+		}
+		for _, ev := range evs {
+			if replaceableKey(ev) == "" {
+				db.DeleteEvent(ctx, ev)
+			}
+			if ev.CreatedAt <= until {
+				until = ev.CreatedAt - 1
+			}
+		}
+	}
+}
+
+// Close is
+func (cfg *Config) Close() {
+	if cfg.db != nil {
+		cfg.db.Close()
+		cfg.db = nil
+	}
+	if bs, ok := cfg.signer.(*BunkerSigner); ok {
+		bs.Close()
+	}
+}
+
+func (cfg *Config) cachedEvents(filter nostr.Filter) []*nostr.Event {
+	// the cache can not do full text search
+	if filter.Search != "" {
+		return nil
+	}
+	db := cfg.cache()
+	if db == nil {
+		return nil
+	}
+	ch, err := db.QueryEvents(context.Background(), filter)
+	if err != nil {
+		return nil
+	}
+	var evs []*nostr.Event
+	for ev := range ch {
+		evs = append(evs, ev)
+	}
+	return evs
+}
+
+// cacheEvent stores the raw event, replacing older versions of replaceable
+// events. It must be called before the content is decrypted.
+func (cfg *Config) cacheEvent(ev *nostr.Event) {
+	db := cfg.cache()
+	if db == nil {
+		return
+	}
+	ctx := context.Background()
+
+	// relays call this at once. badger locks the directory against other
+	// processes, so this makes the check, delete and save below atomic.
+	cfg.cacheMu.Lock()
+	defer cfg.cacheMu.Unlock()
+
+	var filter nostr.Filter
+	switch {
+	case 20000 <= ev.Kind && ev.Kind < 30000:
+		return
+	case ev.Kind == nostr.KindProfileMetadata || ev.Kind == nostr.KindFollowList || (10000 <= ev.Kind && ev.Kind < 20000):
+		filter = nostr.Filter{Kinds: []int{ev.Kind}, Authors: []string{ev.PubKey}}
+	case 30000 <= ev.Kind && ev.Kind < 40000:
+		filter = nostr.Filter{Kinds: []int{ev.Kind}, Authors: []string{ev.PubKey}, Tags: nostr.TagMap{"d": []string{ev.Tags.GetD()}}}
+	}
+	if len(filter.Kinds) > 0 {
+		for _, old := range cfg.cachedEvents(filter) {
+			if old.CreatedAt >= ev.CreatedAt {
+				return
+			}
+			db.DeleteEvent(ctx, old)
+		}
+	}
+	db.SaveEvent(ctx, ev)
+}
@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+func TestCacheEventReplaceable(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	cfg := &Config{}
+	defer cfg.Close()
+
+	sk := nostr.GeneratePrivateKey()
+	pub, _ := nostr.GetPublicKey(sk)
+	now := nostr.Now()
+
+	// versions come from relays at once
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		ev := &nostr.Event{
+			Kind:      nostr.KindProfileMetadata,
+			CreatedAt: now - nostr.Timestamp(i),
+			Tags:      nostr.Tags{},
+			Content:   "{}",
+		}
+		if err := ev.Sign(sk); err != nil {
+			t.Fatal(err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cfg.cacheEvent(ev)
+		}()
+	}
+	wg.Wait()
+
+	evs := cfg.cachedEvents(nostr.Filter{Kinds: []int{nostr.KindProfileMetadata}, Authors: []string{pub}})
+	if len(evs) != 1 {
+		t.Fatalf("want 1 cached version, but got %d", len(evs))
+	}
+	if evs[0].CreatedAt != now {
+		t.Fatalf("want the latest version at %d, but got %d", now, evs[0].CreatedAt)
+	}
+}
+
+func TestPruneCache(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	cfg := &Config{}
+	defer cfg.Close()
+
+	sk := nostr.GeneratePrivateKey()
+	pub, _ := nostr.GetPublicKey(sk)
+	now := nostr.Now()
+	old := now - 60*24*60*60
+
+	for _, ev := range []*nostr.Event{
+		{Kind: nostr.KindTextNote, CreatedAt: now, Content: "new"},
+		{Kind: nostr.KindTextNote, CreatedAt: old, Content: "old"},
+		{Kind: nostr.KindTextNote, CreatedAt: old, Content: "old too"},
+		{Kind: nostr.KindProfileMetadata, CreatedAt: old, Content: "{}"},
+	} {
+		ev.Tags = nostr.Tags{}
+		if err := ev.Sign(sk); err != nil {
+			t.Fatal(err)
+		}
+		cfg.cacheEvent(ev)
+	}
+	pruneCache(cfg.cache(), now-30*24*60*60)
+
+	evs := cfg.cachedEvents(nostr.Filter{Authors: []string{pub}})
+	if len(evs) != 2 {
+		t.Fatalf("want 2 events, but got %d", len(evs))
+	}
+	for _, ev := range evs {
+		if ev.CreatedAt == old && ev.Kind != nostr.KindProfileMetadata {
+			t.Fatalf("want old note pruned, but got %q", ev.Content)
+		}
+	}
+}
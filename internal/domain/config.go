@@ -6,8 +6,8 @@ import (
 	"errors"
 	"fmt"
 	"github.com/fatih/color"
+	"github.com/fiatjaf/eventstore/badger"
 	"github.com/nbd-wtf/go-nostr"
-	"github.com/nbd-wtf/go-nostr/nip04"
 	"github.com/nbd-wtf/go-nostr/nip19"
 	"net/http"
 	"os"
@@ -21,16 +21,34 @@ import (
 
 // Config is
 type Config struct {
-	Relays     map[string]Relay   `json:"relays"`
-	Follows    map[string]Profile `json:"follows"`
-	PrivateKey string             `json:"privatekey"`
-	Updated    time.Time          `json:"updated"`
-	Emojis     map[string]string  `json:"emojis"`
-	NwcURI     string             `json:"nwc-uri"`
-	NwcPub     string             `json:"nwc-pub"`
-	Verbose    bool
-	TempRelay  bool
-	sk         string
+	Relays          map[string]Relay   `json:"relays"`
+	Follows         map[string]Profile `json:"follows"`
+	PrivateKey      string             `json:"privatekey"`
+	Updated         time.Time          `json:"updated"`
+	Emojis          map[string]string  `json:"emojis"`
+	NwcURI          string             `json:"nwc-uri"`
+	NwcPub          string             `json:"nwc-pub"`
+	Outbox          bool               `json:"outbox"`
+	Bunker          string             `json:"bunker,omitempty"`
+	BunkerClientKey string             `json:"bunker-clientkey,omitempty"`
+	RemovedRelays   []string           `json:"removed-relays,omitempty"`
+	LastSeen        nostr.Timestamp    `json:"last-seen,omitempty"`
+	DraftSync       bool               `json:"draft-sync,omitempty"`
+	Verbose         bool
+	TempRelay       bool
+	Offline         bool `json:"-"`
+	SinceCache      bool `json:"-"`
+	PassphraseFD    int  `json:"-"`
+	RawContent      bool `json:"-"`
+	profile         string
+	passphrase      string
+	signer          Signer
+	signerMu        sync.Mutex
+	profiles        map[string]*Profile
+	quoted          map[string]*nostr.Event
+	db              *badger.BadgerBackend
+	cacheOnce       sync.Once
+	cacheMu         sync.Mutex
 }
 
 func ConfigDir() (string, error) {
@@ -46,6 +64,19 @@ func ConfigDir() (string, error) {
 	}
 }
 
+// ConfigFile returns the path of the config file for the profile.
+func ConfigFile(profile string) (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	dir = filepath.Join(dir, "algia")
+	if profile == "" {
+		return filepath.Join(dir, "config.json"), nil
+	}
+	return filepath.Join(dir, "config-"+profile+".json"), nil
+}
+
 func LoadConfig(profile string) (*Config, error) {
 	dir, err := ConfigDir()
 	if err != nil {
@@ -81,6 +112,7 @@ func LoadConfig(profile string) (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	cfg.profile = profile
 	if len(cfg.Relays) == 0 {
 		cfg.Relays = map[string]Relay{}
 		cfg.Relays["wss://relay.nostr.band"] = Relay{
@@ -95,15 +127,18 @@ func LoadConfig(profile string) (*Config, error) {
 // GetFollows is
 func (cfg *Config) GetFollows(profile string) (map[string]Profile, error) {
 	var mu sync.Mutex
-	var pub string
-	if _, s, err := nip19.Decode(cfg.PrivateKey); err == nil {
-		if pub, err = nostr.GetPublicKey(s.(string)); err != nil {
-			return nil, err
-		}
-	} else {
+	pub, err := cfg.PublicKey()
+	if err != nil {
 		return nil, err
 	}
 
+	if cfg.Offline {
+		if len(cfg.Follows) == 0 {
+			cfg.Follows = cfg.cachedFollows(pub)
+		}
+		return cfg.Follows, nil
+	}
+
 	// get followers
 	if (cfg.Updated.Add(3*time.Hour).Before(time.Now()) && !cfg.TempRelay) || len(cfg.Follows) == 0 {
 		mu.Lock()
@@ -112,22 +147,12 @@ func (cfg *Config) GetFollows(profile string) (map[string]Profile, error) {
 		m := map[string]struct{}{}
 
 		cfg.Do(Relay{Read: true}, func(ctx context.Context, relay *nostr.Relay) bool {
-			evs, err := relay.QuerySync(ctx, nostr.Filter{Kinds: []int{nostr.KindContactList}, Authors: []string{pub}, Limit: 1})
+			evs, err := relay.QuerySync(ctx, nostr.Filter{Kinds: []int{nostr.KindFollowList}, Authors: []string{pub}, Limit: 1})
 			if err != nil {
 				return true
 			}
 			for _, ev := range evs {
-				var rm map[string]Relay
-				if cfg.TempRelay == false {
-					if err := json.Unmarshal([]byte(ev.Content), &rm); err == nil {
-						for k, v1 := range cfg.Relays {
-							if v2, ok := rm[k]; ok {
-								v2.Search = v1.Search
-							}
-						}
-						cfg.Relays = rm
-					}
-				}
+				cfg.cacheEvent(ev)
 				for _, tag := range ev.Tags {
 					if len(tag) >= 2 && tag[0] == "p" {
 						mu.Lock()
@@ -141,6 +166,13 @@ func (cfg *Config) GetFollows(profile string) (map[string]Profile, error) {
 		if cfg.Verbose {
 			fmt.Printf("found %d followers\n", len(m))
 		}
+
+		// merge relay list metadata
+		if !cfg.TempRelay {
+			if rm, _ := cfg.GetRelayList(pub); rm != nil {
+				cfg.MergeRelays(rm)
+			}
+		}
 		if len(m) > 0 {
 			follows := []string{}
 			for k := range m {
@@ -164,6 +196,7 @@ func (cfg *Config) GetFollows(profile string) (map[string]Profile, error) {
 						return true
 					}
 					for _, ev := range evs {
+						cfg.cacheEvent(ev)
 						var profile Profile
 						err := json.Unmarshal([]byte(ev.Content), &profile)
 						if err == nil {
@@ -178,7 +211,7 @@ func (cfg *Config) GetFollows(profile string) (map[string]Profile, error) {
 		}
 
 		cfg.Updated = time.Now()
-		if err := cfg.save(profile); err != nil {
+		if err := cfg.Save(profile); err != nil {
 			return nil, err
 		}
 	}
@@ -212,10 +245,102 @@ func (cfg *Config) FindRelay(ctx context.Context, r Relay) *nostr.Relay {
 	return nil
 }
 
+// GetRelayList is
+func (cfg *Config) GetRelayList(pub string) (map[string]Relay, *nostr.Event) {
+	var mu sync.Mutex
+	filter := nostr.Filter{Kinds: []int{nostr.KindRelayListMetadata}, Authors: []string{pub}, Limit: 1}
+	var latest *nostr.Event
+	for _, ev := range cfg.cachedEvents(filter) {
+		latest = ev
+	}
+	cfg.Do(Relay{Read: true}, func(ctx context.Context, relay *nostr.Relay) bool {
+		evs, err := relay.QuerySync(ctx, filter)
+		if err != nil {
+			return true
+		}
+		mu.Lock()
+		for _, ev := range evs {
+			if latest == nil || ev.CreatedAt > latest.CreatedAt {
+				latest = ev
+			}
+		}
+		mu.Unlock()
+		return true
+	})
+	if latest == nil {
+		return nil, nil
+	}
+	cfg.cacheEvent(latest)
+	return ParseRelayList(latest), latest
+}
+
+// MergeRelays is
+func (cfg *Config) MergeRelays(rm map[string]Relay) {
+	if cfg.Relays == nil {
+		cfg.Relays = map[string]Relay{}
+	}
+	known := map[string]struct{}{}
+	for k := range cfg.Relays {
+		known[nostr.NormalizeURL(k)] = struct{}{}
+	}
+	// relays removed locally are not brought back
+	for _, k := range cfg.RemovedRelays {
+		known[nostr.NormalizeURL(k)] = struct{}{}
+	}
+	for k, v := range rm {
+		if _, ok := known[nostr.NormalizeURL(k)]; ok {
+			continue
+		}
+		cfg.Relays[k] = v
+	}
+}
+
+// AddRelay is
+func (cfg *Config) AddRelay(u string, r Relay) {
+	if cfg.Relays == nil {
+		cfg.Relays = map[string]Relay{}
+	}
+	for k := range cfg.Relays {
+		if nostr.NormalizeURL(k) == nostr.NormalizeURL(u) {
+			delete(cfg.Relays, k)
+		}
+	}
+	cfg.Relays[u] = r
+	removed := cfg.RemovedRelays[:0]
+	for _, k := range cfg.RemovedRelays {
+		if nostr.NormalizeURL(k) != nostr.NormalizeURL(u) {
+			removed = append(removed, k)
+		}
+	}
+	cfg.RemovedRelays = removed
+}
+
+// RemoveRelay removes the relay and remembers it, so MergeRelays does not add
+// it again from the relay list. false is returned if it is not configured.
+func (cfg *Config) RemoveRelay(u string) bool {
+	found := false
+	for k := range cfg.Relays {
+		if nostr.NormalizeURL(k) == nostr.NormalizeURL(u) {
+			delete(cfg.Relays, k)
+			found = true
+		}
+	}
+	if found {
+		cfg.RemovedRelays = append(cfg.RemovedRelays, u)
+	}
+	return found
+}
+
 // Do is
 func (cfg *Config) Do(r Relay, f func(context.Context, *nostr.Relay) bool) {
-	var wg sync.WaitGroup
-	ctx := context.Background()
+	if cfg.Offline && !r.Write {
+		return
+	}
+	if !r.Write {
+		// read results are cached, open it before spawning goroutines
+		cfg.cache()
+	}
+	var urls []string
 	for k, v := range cfg.Relays {
 		if r.Write && !v.Write {
 			continue
@@ -226,8 +351,18 @@ func (cfg *Config) Do(r Relay, f func(context.Context, *nostr.Relay) bool) {
 		if !r.Write && !v.Read {
 			continue
 		}
+		urls = append(urls, k)
+	}
+	cfg.DoURLs(urls, f)
+}
+
+// DoURLs is
+func (cfg *Config) DoURLs(urls []string, f func(context.Context, *nostr.Relay) bool) {
+	var wg sync.WaitGroup
+	ctx := context.Background()
+	for _, k := range urls {
 		wg.Add(1)
-		go func(wg *sync.WaitGroup, k string, v Relay) {
+		go func(wg *sync.WaitGroup, k string) {
 			defer wg.Done()
 			relay, err := nostr.RelayConnect(ctx, k)
 			if err != nil {
@@ -240,45 +375,48 @@ func (cfg *Config) Do(r Relay, f func(context.Context, *nostr.Relay) bool) {
 				ctx.Done()
 			}
 			relay.Close()
-		}(&wg, k, v)
+		}(&wg, k)
 	}
 	wg.Wait()
 }
 
-func (cfg *Config) save(profile string) error {
+// Save is
+func (cfg *Config) Save(profile string) error {
 	if cfg.TempRelay {
 		return nil
 	}
-	dir, err := ConfigDir()
+	fp, err := ConfigFile(profile)
 	if err != nil {
 		return err
 	}
-	dir = filepath.Join(dir, "algia")
-
-	var fp string
-	if profile == "" {
-		fp = filepath.Join(dir, "config.json")
-	} else {
-		fp = filepath.Join(dir, "config-"+profile+".json")
-	}
 	b, err := json.MarshalIndent(&cfg, "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(fp, b, 0644)
+	if err := os.WriteFile(fp, b, 0600); err != nil {
+		return err
+	}
+	// the file may be created with wider permission before
+	return os.Chmod(fp, 0600)
 }
 
 // Decode is
 func (cfg *Config) Decode(ev *nostr.Event) error {
-	var sk string
-	var pub string
-	if _, s, err := nip19.Decode(cfg.PrivateKey); err == nil {
-		sk = s.(string)
-		if pub, err = nostr.GetPublicKey(s.(string)); err != nil {
+	signer, err := cfg.Signer()
+	if err != nil {
+		return err
+	}
+	pub, err := signer.GetPublicKey(context.TODO())
+	if err != nil {
+		return err
+	}
+	if ev.Kind == KindGiftWrap {
+		rumor, err := unwrap(ev, signer)
+		if err != nil {
 			return err
 		}
-	} else {
-		return err
+		*ev = *rumor
+		return nil
 	}
 	tag := ev.Tags.GetFirst([]string{"p"})
 	sp := pub
@@ -292,11 +430,7 @@ func (cfg *Config) Decode(ev *nostr.Event) error {
 			sp = ev.PubKey
 		}
 	}
-	ss, err := nip04.ComputeSharedSecret(sp, sk)
-	if err != nil {
-		return err
-	}
-	content, err := nip04.Decrypt(ev.Content, ss)
+	content, err := signer.Nip04Decrypt(context.TODO(), sp, ev.Content)
 	if err != nil {
 		return err
 	}
@@ -305,7 +439,7 @@ func (cfg *Config) Decode(ev *nostr.Event) error {
 }
 
 // PrintEvents is
-func (cfg *Config) PrintEvents(evs []*nostr.Event, followsMap map[string]Profile, j, extra bool) {
+func (cfg *Config) PrintEvents(evs []*nostr.Event, followsMap map[string]Profile, stats map[string]*Stats, j, extra bool) {
 	if j {
 		if extra {
 			var events []Event
@@ -314,6 +448,7 @@ func (cfg *Config) PrintEvents(evs []*nostr.Event, followsMap map[string]Profile
 					events = append(events, Event{
 						Event:   ev,
 						Profile: profile,
+						Stats:   stats[ev.ID],
 					})
 				}
 			}
@@ -328,37 +463,121 @@ func (cfg *Config) PrintEvents(evs []*nostr.Event, followsMap map[string]Profile
 		return
 	}
 
+	if !cfg.RawContent {
+		cfg.fetchReferences(evs, followsMap)
+	}
 	for _, ev := range evs {
-		profile, ok := followsMap[ev.PubKey]
-		if ok {
-			color.Set(color.FgHiRed)
-			fmt.Print(profile.Name)
-		} else {
-			color.Set(color.FgRed)
-			if pk, err := nip19.EncodePublicKey(ev.PubKey); err == nil {
-				fmt.Print(pk)
-			} else {
-				fmt.Print(ev.PubKey)
-			}
+		cfg.PrintEvent(ev, followsMap, "")
+		if s, ok := stats[ev.ID]; ok {
+			cfg.PrintStats(s, "")
 		}
-		color.Set(color.Reset)
-		fmt.Print(": ")
-		color.Set(color.FgHiBlue)
-		if ni, err := nip19.EncodeNote(ev.ID); err == nil {
-			fmt.Println(ni)
+	}
+}
+
+// PrintEvent is
+func (cfg *Config) PrintEvent(ev *nostr.Event, followsMap map[string]Profile, indent string) {
+	fmt.Print(indent)
+	profile, ok := followsMap[ev.PubKey]
+	if ok {
+		color.Set(color.FgHiRed)
+		fmt.Print(profile.Name)
+	} else {
+		color.Set(color.FgRed)
+		if pk, err := nip19.EncodePublicKey(ev.PubKey); err == nil {
+			fmt.Print(pk)
 		} else {
-			fmt.Println(ev.ID)
+			fmt.Print(ev.PubKey)
 		}
-		color.Set(color.Reset)
-		fmt.Println(ev.Content)
+	}
+	color.Set(color.Reset)
+	fmt.Print(": ")
+	color.Set(color.FgHiBlue)
+	if ni, err := nip19.EncodeNote(ev.ID); err == nil {
+		fmt.Println(ni)
+	} else {
+		fmt.Println(ev.ID)
+	}
+	color.Set(color.Reset)
+	content := cfg.RenderContent(ev, followsMap)
+	if indent == "" {
+		fmt.Println(content)
+		return
+	}
+	for _, line := range strings.Split(content, "\n") {
+		fmt.Println(indent + line)
 	}
 }
 
 // Events is
 func (cfg *Config) Events(filter nostr.Filter) []*nostr.Event {
+	var m sync.Map
+	cached := cfg.cachedEvents(filter)
+	for _, ev := range cached {
+		if err := cfg.decodeEvent(ev); err == nil {
+			m.Store(ev.ID, ev)
+		}
+	}
+	if cfg.Offline || (len(filter.IDs) > 0 && len(cached) >= len(filter.IDs)) {
+		return latestEvents(sortEvents(&m))
+	}
+	if cfg.SinceCache && len(cached) > 0 {
+		since := cached[0].CreatedAt
+		for _, ev := range cached {
+			if ev.CreatedAt > since {
+				since = ev.CreatedAt
+			}
+		}
+		if filter.Since == nil || *filter.Since < since {
+			filter.Since = &since
+		}
+	}
+
+	if cfg.Outbox && len(filter.Authors) > 0 && len(filter.IDs) == 0 {
+		cfg.outboxEvents(filter, &m)
+	} else {
+		cfg.relayEvents(filter, &m)
+	}
+	// the cached one and the one from relays may be different versions
+	return latestEvents(sortEvents(&m))
+}
+
+// CollectEvents is Events which queries again with older until while relays
+// return partial results, until n events are collected.
+func (cfg *Config) CollectEvents(filter nostr.Filter, n int) []*nostr.Event {
+	evs := cfg.Events(filter)
+	if n <= 0 || cfg.Offline {
+		return evs
+	}
+	seen := map[string]struct{}{}
+	for _, ev := range evs {
+		seen[ev.ID] = struct{}{}
+	}
+	for i := 0; i < 10 && len(evs) > 0 && len(evs) < n; i++ {
+		until := evs[0].CreatedAt
+		filter.Until = &until
+		filter.Limit = n - len(evs) + 1
+		var older []*nostr.Event
+		for _, ev := range cfg.Events(filter) {
+			if _, ok := seen[ev.ID]; ok {
+				continue
+			}
+			seen[ev.ID] = struct{}{}
+			older = append(older, ev)
+		}
+		if len(older) == 0 {
+			break
+		}
+		evs = append(older, evs...)
+	}
+	if len(evs) > n {
+		evs = evs[len(evs)-n:]
+	}
+	return evs
+}
+
+func (cfg *Config) relayEvents(filter nostr.Filter, m *sync.Map) {
 	var mu sync.Mutex
 	found := false
-	var m sync.Map
 	cfg.Do(Relay{Read: true}, func(ctx context.Context, relay *nostr.Relay) bool {
 		mu.Lock()
 		if found {
@@ -371,25 +590,63 @@ func (cfg *Config) Events(filter nostr.Filter) []*nostr.Event {
 			return true
 		}
 		for _, ev := range evs {
-			if _, ok := m.Load(ev.ID); !ok {
-				if ev.Kind == nostr.KindEncryptedDirectMessage || ev.Kind == nostr.KindCategorizedBookmarksList {
-					if err := cfg.Decode(ev); err != nil {
-						continue
-					}
-				}
-				m.LoadOrStore(ev.ID, ev)
-				if len(filter.IDs) == 1 {
-					mu.Lock()
-					found = true
-					ctx.Done()
-					mu.Unlock()
-					break
-				}
+			if !cfg.storeEvent(m, ev) {
+				continue
+			}
+			if len(filter.IDs) == 1 {
+				mu.Lock()
+				found = true
+				ctx.Done()
+				mu.Unlock()
+				break
 			}
 		}
 		return true
 	})
+}
+
+func (cfg *Config) storeEvent(m *sync.Map, ev *nostr.Event) bool {
+	if _, ok := m.Load(ev.ID); ok {
+		return false
+	}
+	cfg.cacheEvent(ev)
+	if err := cfg.decodeEvent(ev); err != nil {
+		return false
+	}
+	_, loaded := m.LoadOrStore(ev.ID, ev)
+	return !loaded
+}
+
+func (cfg *Config) decodeEvent(ev *nostr.Event) error {
+	if ev.Kind == nostr.KindEncryptedDirectMessage || ev.Kind == nostr.KindCategorizedBookmarksList || ev.Kind == KindGiftWrap {
+		return cfg.Decode(ev)
+	}
+	return nil
+}
+
+func (cfg *Config) cachedFollows(pub string) map[string]Profile {
+	follows := map[string]Profile{}
+	var authors []string
+	for _, ev := range cfg.cachedEvents(nostr.Filter{Kinds: []int{nostr.KindFollowList}, Authors: []string{pub}, Limit: 1}) {
+		for _, tag := range ev.Tags {
+			if len(tag) >= 2 && tag[0] == "p" {
+				authors = append(authors, tag[1])
+			}
+		}
+	}
+	if len(authors) == 0 {
+		return follows
+	}
+	for _, ev := range cfg.cachedEvents(nostr.Filter{Kinds: []int{nostr.KindProfileMetadata}, Authors: authors}) {
+		var profile Profile
+		if err := json.Unmarshal([]byte(ev.Content), &profile); err == nil {
+			follows[ev.PubKey] = profile
+		}
+	}
+	return follows
+}
 
+func sortEvents(m *sync.Map) []*nostr.Event {
 	keys := []string{}
 	m.Range(func(k, v any) bool {
 		keys = append(keys, k.(string))
@@ -417,6 +674,42 @@ func (cfg *Config) Events(filter nostr.Filter) []*nostr.Event {
 	return evs
 }
 
+// replaceableKey returns the key shared by the versions of the replaceable
+// event. Empty string is returned for other events.
+func replaceableKey(ev *nostr.Event) string {
+	switch {
+	case ev.Kind == nostr.KindProfileMetadata || ev.Kind == nostr.KindFollowList || (10000 <= ev.Kind && ev.Kind < 20000):
+		return fmt.Sprintf("%d:%s", ev.Kind, ev.PubKey)
+	case 30000 <= ev.Kind && ev.Kind < 40000:
+		return fmt.Sprintf("%d:%s:%s", ev.Kind, ev.PubKey, ev.Tags.GetD())
+	}
+	return ""
+}
+
+// latestEvents drops the replaced versions of replaceable events.
+func latestEvents(evs []*nostr.Event) []*nostr.Event {
+	latest := map[string]*nostr.Event{}
+	for _, ev := range evs {
+		key := replaceableKey(ev)
+		if key == "" {
+			continue
+		}
+		if old, ok := latest[key]; !ok || ev.CreatedAt > old.CreatedAt {
+			latest[key] = ev
+		}
+	}
+	if len(latest) == 0 {
+		return evs
+	}
+	var result []*nostr.Event
+	for _, ev := range evs {
+		if key := replaceableKey(ev); key == "" || latest[key] == ev {
+			result = append(result, ev)
+		}
+	}
+	return result
+}
+
 // ZapInfo is
 func (cfg *Config) ZapInfo(pub string) (*Lnurlp, error) {
 	relay := cfg.FindRelay(context.Background(), Relay{Read: true})
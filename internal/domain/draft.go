@@ -0,0 +1,230 @@
+package domain
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+const KindDraft = 31234
+
+// Draft is
+type Draft struct {
+	ID    string       `json:"id"`
+	Event *nostr.Event `json:"event"`
+}
+
+// NewDraftID returns a random identifier for a new draft.
+func NewDraftID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// profileDir returns the directory for the name in the algia config
+// directory. The profile name is added for profiles.
+func (cfg *Config) profileDir(name string) (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	if cfg.profile != "" {
+		name += "-" + cfg.profile
+	}
+	return filepath.Join(dir, "algia", name), nil
+}
+
+func (cfg *Config) draftFile(id string) (string, error) {
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return "", fmt.Errorf("invalid draft id '%s'", id)
+	}
+	dir, err := cfg.profileDir("drafts")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, id+".json"), nil
+}
+
+// Drafts returns the local drafts, older first.
+func (cfg *Config) Drafts() ([]Draft, error) {
+	dir, err := cfg.profileDir("drafts")
+	if err != nil {
+		return nil, err
+	}
+	names, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var drafts []Draft
+	for _, name := range names {
+		id := strings.TrimSuffix(filepath.Base(name), ".json")
+		ev, err := cfg.LoadDraft(id)
+		if err != nil {
+			return nil, err
+		}
+		drafts = append(drafts, Draft{ID: id, Event: ev})
+	}
+	sort.Slice(drafts, func(i, j int) bool {
+		return drafts[i].Event.CreatedAt < drafts[j].Event.CreatedAt
+	})
+	return drafts, nil
+}
+
+// LoadDraft is
+func (cfg *Config) LoadDraft(id string) (*nostr.Event, error) {
+	fp, err := cfg.draftFile(id)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(fp)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("draft '%s' not found", id)
+		}
+		return nil, err
+	}
+	var ev nostr.Event
+	if err := json.Unmarshal(b, &ev); err != nil {
+		return nil, fmt.Errorf("broken draft '%s': %w", id, err)
+	}
+	return &ev, nil
+}
+
+// SaveDraft stores the unsigned event as the draft.
+func (cfg *Config) SaveDraft(id string, ev *nostr.Event) error {
+	fp, err := cfg.draftFile(id)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(fp), 0700); err != nil {
+		return err
+	}
+	ev.ID, ev.Sig = "", ""
+	b, err := json.MarshalIndent(ev, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fp, b, 0600)
+}
+
+// RemoveDraft is
+func (cfg *Config) RemoveDraft(id string) error {
+	fp, err := cfg.draftFile(id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(fp); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// PushDraft publishes the draft encrypted to yourself as NIP-37 draft event.
+// If ev is nil, the draft on the relays is blanked to delete it.
+func (cfg *Config) PushDraft(id string, ev *nostr.Event) error {
+	signer, err := cfg.Signer()
+	if err != nil {
+		return err
+	}
+	pub, err := signer.GetPublicKey(context.TODO())
+	if err != nil {
+		return err
+	}
+
+	wrap := nostr.Event{
+		PubKey:    pub,
+		Kind:      KindDraft,
+		CreatedAt: nostr.Now(),
+		Tags:      nostr.Tags{{"d", id}},
+	}
+	if ev != nil {
+		wrap.CreatedAt = ev.CreatedAt
+		wrap.Tags = append(wrap.Tags, nostr.Tag{"k", strconv.Itoa(ev.Kind)})
+		wrap.Content, err = signer.Nip44Encrypt(context.TODO(), pub, ev.String())
+		if err != nil {
+			return err
+		}
+	}
+	if err := signer.SignEvent(context.TODO(), &wrap); err != nil {
+		return err
+	}
+
+	var success atomic.Int64
+	cfg.Do(Relay{Write: true}, func(ctx context.Context, relay *nostr.Relay) bool {
+		err := relay.Publish(ctx, wrap)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, relay.URL, err)
+		} else {
+			success.Add(1)
+		}
+		return true
+	})
+	if success.Load() == 0 {
+		return errors.New("cannot sync draft")
+	}
+	return nil
+}
+
+// PullDrafts updates the local drafts with newer NIP-37 draft events on the
+// relays. Blanked drafts are removed.
+func (cfg *Config) PullDrafts() error {
+	signer, err := cfg.Signer()
+	if err != nil {
+		return err
+	}
+	pub, err := signer.GetPublicKey(context.TODO())
+	if err != nil {
+		return err
+	}
+
+	latest := map[string]*nostr.Event{}
+	for _, wrap := range cfg.Events(nostr.Filter{Kinds: []int{KindDraft}, Authors: []string{pub}}) {
+		// drafts of other clients may not be stored as files
+		id := wrap.Tags.GetD()
+		if _, err := cfg.draftFile(id); err != nil {
+			continue
+		}
+		// blanked one wins if the time is same
+		if old, ok := latest[id]; !ok || wrap.CreatedAt > old.CreatedAt || (wrap.CreatedAt == old.CreatedAt && wrap.Content == "") {
+			latest[id] = wrap
+		}
+	}
+
+	for id, wrap := range latest {
+		if ev, err := cfg.LoadDraft(id); err == nil && (ev.CreatedAt > wrap.CreatedAt || (ev.CreatedAt == wrap.CreatedAt && wrap.Content != "")) {
+			continue
+		}
+		if wrap.Content == "" {
+			if err := cfg.RemoveDraft(id); err != nil {
+				return err
+			}
+			continue
+		}
+		content, err := signer.Nip44Decrypt(context.TODO(), pub, wrap.Content)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "cannot decrypt draft", id, err)
+			continue
+		}
+		var ev nostr.Event
+		if err := json.Unmarshal([]byte(content), &ev); err != nil {
+			fmt.Fprintln(os.Stderr, "broken draft", id, err)
+			continue
+		}
+		ev.CreatedAt = wrap.CreatedAt
+		if err := cfg.SaveDraft(id, &ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,9 +1,29 @@
 package domain
 
-import "github.com/nbd-wtf/go-nostr"
+import (
+	"errors"
+	"strings"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+)
+
+const KindGenericRepost = 16
 
 // Event is
 type Event struct {
 	Event   *nostr.Event `json:"event"`
 	Profile Profile      `json:"profile"`
+	Stats   *Stats       `json:"stats,omitempty"`
+}
+
+// DecodeEntity is nip19.Decode which accepts nostr: URI and never panics on
+// broken input.
+func DecodeEntity(input string) (prefix string, value any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			prefix, value, err = "", nil, errors.New("malformed data")
+		}
+	}()
+	return nip19.Decode(strings.TrimPrefix(input, "nostr:"))
 }
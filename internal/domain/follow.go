@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// ContactList returns the newest contact list of pub found in the cache and
+// on all read relays, and the largest number of follows seen in any copy.
+func (cfg *Config) ContactList(pub string) (*nostr.Event, int) {
+	var mu sync.Mutex
+	filter := nostr.Filter{Kinds: []int{nostr.KindFollowList}, Authors: []string{pub}, Limit: 1}
+
+	var latest *nostr.Event
+	most := 0
+	add := func(ev *nostr.Event) {
+		if n := len(ev.Tags.GetAll([]string{"p", ""})); n > most {
+			most = n
+		}
+		if latest == nil || ev.CreatedAt > latest.CreatedAt {
+			latest = ev
+		}
+	}
+	for _, ev := range cfg.cachedEvents(filter) {
+		add(ev)
+	}
+	cfg.Do(Relay{Read: true}, func(ctx context.Context, relay *nostr.Relay) bool {
+		evs, err := relay.QuerySync(ctx, filter)
+		if err != nil {
+			return true
+		}
+		mu.Lock()
+		for _, ev := range evs {
+			add(ev)
+		}
+		mu.Unlock()
+		return true
+	})
+	if latest != nil {
+		cfg.cacheEvent(latest)
+	}
+	return latest, most
+}
+
+// UpdateFollows replaces the cached follows with the p tags of the contact list.
+func (cfg *Config) UpdateFollows(profile string, ev *nostr.Event) error {
+	cfg.cacheEvent(ev)
+
+	follows := map[string]Profile{}
+	missing := []string{}
+	for _, tag := range ev.Tags.GetAll([]string{"p", ""}) {
+		if p, ok := cfg.Follows[tag[1]]; ok {
+			follows[tag[1]] = p
+		} else {
+			missing = append(missing, tag[1])
+		}
+	}
+
+	if len(missing) > 0 && !cfg.Offline {
+		var mu sync.Mutex
+		cfg.Do(Relay{Read: true}, func(ctx context.Context, relay *nostr.Relay) bool {
+			evs, err := relay.QuerySync(ctx, nostr.Filter{
+				Kinds:   []int{nostr.KindProfileMetadata},
+				Authors: missing,
+			})
+			if err != nil {
+				return true
+			}
+			for _, ev := range evs {
+				cfg.cacheEvent(ev)
+				var p Profile
+				if err := json.Unmarshal([]byte(ev.Content), &p); err == nil {
+					mu.Lock()
+					follows[ev.PubKey] = p
+					mu.Unlock()
+				}
+			}
+			return true
+		})
+	}
+
+	cfg.Follows = follows
+	cfg.Updated = time.Now()
+	return cfg.Save(profile)
+}
@@ -0,0 +1,170 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"sync"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip44"
+)
+
+const (
+	KindSeal                 = 13
+	KindPrivateDirectMessage = 14
+	KindGiftWrap             = 1059
+	KindDMRelayList          = 10050
+)
+
+// randomNow returns a time up to two days in the past to hide the real one.
+func randomNow() nostr.Timestamp {
+	return nostr.Now() - nostr.Timestamp(rand.Int63n(2*24*60*60))
+}
+
+// GiftWrap is
+func (cfg *Config) GiftWrap(rumor nostr.Event, recipient string) (*nostr.Event, error) {
+	signer, err := cfg.Signer()
+	if err != nil {
+		return nil, err
+	}
+
+	// the rumor is never signed
+	rumor.Sig = ""
+	rumor.ID = rumor.GetID()
+	b, err := json.Marshal(rumor)
+	if err != nil {
+		return nil, err
+	}
+	content, err := signer.Nip44Encrypt(context.TODO(), recipient, string(b))
+	if err != nil {
+		return nil, err
+	}
+	seal := nostr.Event{
+		PubKey:    rumor.PubKey,
+		CreatedAt: randomNow(),
+		Kind:      KindSeal,
+		Tags:      nostr.Tags{},
+		Content:   content,
+	}
+	if err := signer.SignEvent(context.TODO(), &seal); err != nil {
+		return nil, err
+	}
+
+	b, err = json.Marshal(seal)
+	if err != nil {
+		return nil, err
+	}
+	esk := nostr.GeneratePrivateKey()
+	epub, err := nostr.GetPublicKey(esk)
+	if err != nil {
+		return nil, err
+	}
+	ck, err := nip44.GenerateConversationKey(recipient, esk)
+	if err != nil {
+		return nil, err
+	}
+	content, err = nip44.Encrypt(string(b), ck)
+	if err != nil {
+		return nil, err
+	}
+	wrap := nostr.Event{
+		PubKey:    epub,
+		CreatedAt: randomNow(),
+		Kind:      KindGiftWrap,
+		Tags:      nostr.Tags{nostr.Tag{"p", recipient}},
+		Content:   content,
+	}
+	if err := wrap.Sign(esk); err != nil {
+		return nil, err
+	}
+	return &wrap, nil
+}
+
+func unwrap(wrap *nostr.Event, signer Signer) (*nostr.Event, error) {
+	content, err := signer.Nip44Decrypt(context.TODO(), wrap.PubKey, wrap.Content)
+	if err != nil {
+		return nil, err
+	}
+	var seal nostr.Event
+	if err := json.Unmarshal([]byte(content), &seal); err != nil {
+		return nil, err
+	}
+	if seal.Kind != KindSeal {
+		return nil, errors.New("not a seal")
+	}
+	if ok, err := seal.CheckSignature(); err != nil || !ok {
+		return nil, errors.New("invalid seal signature")
+	}
+
+	content, err = signer.Nip44Decrypt(context.TODO(), seal.PubKey, seal.Content)
+	if err != nil {
+		return nil, err
+	}
+	var rumor nostr.Event
+	if err := json.Unmarshal([]byte(content), &rumor); err != nil {
+		return nil, err
+	}
+	if rumor.PubKey != seal.PubKey {
+		return nil, errors.New("rumor is not signed by the author")
+	}
+	return &rumor, nil
+}
+
+// DMRelays is
+func (cfg *Config) DMRelays(pub string) []string {
+	var mu sync.Mutex
+	filter := nostr.Filter{Kinds: []int{KindDMRelayList}, Authors: []string{pub}, Limit: 1}
+	var latest *nostr.Event
+	for _, ev := range cfg.cachedEvents(filter) {
+		latest = ev
+	}
+	cfg.Do(Relay{Read: true}, func(ctx context.Context, relay *nostr.Relay) bool {
+		evs, err := relay.QuerySync(ctx, filter)
+		if err != nil {
+			return true
+		}
+		mu.Lock()
+		for _, ev := range evs {
+			if latest == nil || ev.CreatedAt > latest.CreatedAt {
+				latest = ev
+			}
+		}
+		mu.Unlock()
+		return true
+	})
+	if latest == nil {
+		return nil
+	}
+	cfg.cacheEvent(latest)
+
+	var urls []string
+	for _, tag := range latest.Tags {
+		if len(tag) >= 2 && tag[0] == "relay" && nostr.IsValidRelayURL(tag[1]) {
+			urls = append(urls, nostr.NormalizeURL(tag[1]))
+		}
+	}
+	return urls
+}
+
+// EventsFrom is
+func (cfg *Config) EventsFrom(urls []string, filter nostr.Filter) []*nostr.Event {
+	var m sync.Map
+	for _, ev := range cfg.Events(filter) {
+		m.Store(ev.ID, ev)
+	}
+	if !cfg.Offline {
+		cfg.DoURLs(urls, func(ctx context.Context, relay *nostr.Relay) bool {
+			evs, err := relay.QuerySync(ctx, filter)
+			if err != nil {
+				return true
+			}
+			for _, ev := range evs {
+				cfg.storeEvent(&m, ev)
+			}
+			return true
+		})
+	}
+	return sortEvents(&m)
+}
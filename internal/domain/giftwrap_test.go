@@ -0,0 +1,145 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/nbd-wtf/go-nostr/nip44"
+)
+
+func testKey(t *testing.T) (*KeySigner, *Config) {
+	sk := nostr.GeneratePrivateKey()
+	signer, err := NewKeySigner(sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nsec, err := nip19.EncodePrivateKey(sk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer, &Config{PrivateKey: nsec}
+}
+
+func testRumor(pub, recipient string) nostr.Event {
+	return nostr.Event{
+		PubKey:    pub,
+		CreatedAt: nostr.Now(),
+		Kind:      KindPrivateDirectMessage,
+		Tags:      nostr.Tags{{"p", recipient}},
+		Content:   "hello",
+	}
+}
+
+func TestGiftWrap(t *testing.T) {
+	sender, cfg := testKey(t)
+	recipient, _ := testKey(t)
+
+	rumor := testRumor(sender.pub, recipient.pub)
+	wrap, err := cfg.GiftWrap(rumor, recipient.pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wrap.Kind != KindGiftWrap {
+		t.Fatalf("want kind %d, but got %d", KindGiftWrap, wrap.Kind)
+	}
+	if wrap.PubKey == sender.pub {
+		t.Fatal("want the wrap signed by a random key")
+	}
+	if ok, err := wrap.CheckSignature(); err != nil || !ok {
+		t.Fatalf("invalid wrap signature: %v", err)
+	}
+	if tag := wrap.Tags.GetFirst([]string{"p", recipient.pub}); tag == nil {
+		t.Fatalf("want p tag for the recipient, but got %v", wrap.Tags)
+	}
+
+	got, err := unwrap(wrap, recipient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.PubKey != sender.pub || got.Content != "hello" || got.Kind != KindPrivateDirectMessage {
+		t.Fatalf("want the rumor, but got %v", got)
+	}
+	if got.Sig != "" {
+		t.Fatal("want the rumor unsigned")
+	}
+	if got.ID != got.GetID() {
+		t.Fatalf("want id %s, but got %s", got.GetID(), got.ID)
+	}
+
+	// only the recipient can open it
+	if _, err := unwrap(wrap, sender); err == nil {
+		t.Fatal("want error for unwrapping by the sender")
+	}
+
+	// nip44 must use a random nonce for each message
+	again, err := cfg.GiftWrap(rumor, recipient.pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Content == wrap.Content {
+		t.Fatal("want different ciphertexts for the same rumor")
+	}
+}
+
+func TestGiftWrapSelfCopy(t *testing.T) {
+	sender, cfg := testKey(t)
+	recipient, _ := testKey(t)
+
+	rumor := testRumor(sender.pub, recipient.pub)
+	wrap, err := cfg.GiftWrap(rumor, sender.pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := unwrap(wrap, sender)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.PubKey != sender.pub || got.Content != "hello" {
+		t.Fatalf("want the rumor, but got %v", got)
+	}
+	if tag := got.Tags.GetFirst([]string{"p", recipient.pub}); tag == nil {
+		t.Fatalf("want p tag for the recipient kept, but got %v", got.Tags)
+	}
+}
+
+func TestUnwrapForgedRumor(t *testing.T) {
+	attacker, _ := testKey(t)
+	victim, _ := testKey(t)
+	recipient, _ := testKey(t)
+	ctx := context.Background()
+
+	// the seal is signed by the attacker, but the rumor claims the victim
+	rumor := testRumor(victim.pub, recipient.pub)
+	rumor.ID = rumor.GetID()
+	b, _ := json.Marshal(rumor)
+	content, err := attacker.Nip44Encrypt(ctx, recipient.pub, string(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	seal := nostr.Event{CreatedAt: nostr.Now(), Kind: KindSeal, Tags: nostr.Tags{}, Content: content}
+	if err := attacker.SignEvent(ctx, &seal); err != nil {
+		t.Fatal(err)
+	}
+
+	b, _ = json.Marshal(seal)
+	esk := nostr.GeneratePrivateKey()
+	ck, err := nip44.GenerateConversationKey(recipient.pub, esk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err = nip44.Encrypt(string(b), ck)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrap := nostr.Event{CreatedAt: nostr.Now(), Kind: KindGiftWrap, Tags: nostr.Tags{{"p", recipient.pub}}, Content: content}
+	if err := wrap.Sign(esk); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := unwrap(&wrap, recipient); err == nil {
+		t.Fatal("want error for the rumor not matching the seal")
+	}
+}
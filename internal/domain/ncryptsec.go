@@ -0,0 +1,121 @@
+package domain
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/nbd-wtf/go-nostr/nip49"
+	"golang.org/x/term"
+)
+
+// Passphrase returns the passphrase for ncryptsec. It is read from
+// ALGIA_PASSPHRASE, PassphraseFD or the terminal, only once per run.
+func (cfg *Config) Passphrase(confirm bool) (string, error) {
+	if cfg.passphrase != "" {
+		return cfg.passphrase, nil
+	}
+
+	if s, ok := os.LookupEnv("ALGIA_PASSPHRASE"); ok {
+		cfg.passphrase = s
+	} else if cfg.PassphraseFD >= 0 {
+		f := os.NewFile(uintptr(cfg.PassphraseFD), "passphrase")
+		if f == nil {
+			return "", fmt.Errorf("invalid passphrase fd %d", cfg.PassphraseFD)
+		}
+		line, err := bufio.NewReader(f).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("cannot read passphrase: %w", err)
+		}
+		cfg.passphrase = strings.TrimRight(line, "\r\n")
+	} else {
+		s, err := promptPassphrase("passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if confirm {
+			again, err := promptPassphrase("passphrase (again): ")
+			if err != nil {
+				return "", err
+			}
+			if s != again {
+				return "", errors.New("passphrases do not match")
+			}
+		}
+		cfg.passphrase = s
+	}
+	if cfg.passphrase == "" {
+		return "", errors.New("passphrase is empty")
+	}
+	return cfg.passphrase, nil
+}
+
+func promptPassphrase(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		// stdin may be used for the content
+		name := "/dev/tty"
+		if runtime.GOOS == "windows" {
+			name = "CONIN$"
+		}
+		tty, err := os.Open(name)
+		if err != nil {
+			return "", errors.New("cannot prompt passphrase: set ALGIA_PASSPHRASE or --passphrase-fd")
+		}
+		defer tty.Close()
+		fd = int(tty.Fd())
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	b, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// DecryptKey returns the hex private key, decrypting ncryptsec if needed.
+func (cfg *Config) DecryptKey() (string, error) {
+	if !strings.HasPrefix(cfg.PrivateKey, "ncryptsec") {
+		if _, s, err := nip19.Decode(cfg.PrivateKey); err == nil {
+			return s.(string), nil
+		} else {
+			return "", err
+		}
+	}
+	passphrase, err := cfg.Passphrase(false)
+	if err != nil {
+		return "", err
+	}
+	sk, err := nip49.Decrypt(cfg.PrivateKey, passphrase)
+	if err != nil {
+		cfg.passphrase = ""
+		return "", fmt.Errorf("cannot decrypt privatekey: %w", err)
+	}
+	return sk, nil
+}
+
+// EncryptKey replaces privatekey with ncryptsec.
+func (cfg *Config) EncryptKey(ksb nip49.KeySecurityByte) error {
+	if strings.HasPrefix(cfg.PrivateKey, "ncryptsec") {
+		return errors.New("privatekey is already encrypted")
+	}
+	sk, err := cfg.DecryptKey()
+	if err != nil {
+		return err
+	}
+	passphrase, err := cfg.Passphrase(true)
+	if err != nil {
+		return err
+	}
+	ncryptsec, err := nip49.Encrypt(sk, passphrase, 16, ksb)
+	if err != nil {
+		return err
+	}
+	cfg.PrivateKey = ncryptsec
+	return nil
+}
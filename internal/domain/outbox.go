@@ -0,0 +1,143 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+const (
+	outboxMaxConns        = 8
+	outboxRelaysPerAuthor = 3
+)
+
+// GetRelayLists is
+func (cfg *Config) GetRelayLists(authors []string) map[string]map[string]Relay {
+	var mu sync.Mutex
+	latest := map[string]*nostr.Event{}
+	for _, ev := range cfg.cachedEvents(nostr.Filter{Kinds: []int{nostr.KindRelayListMetadata}, Authors: authors}) {
+		latest[ev.PubKey] = ev
+	}
+	for i := 0; i < len(authors); i += 500 {
+		end := i + 500
+		if end > len(authors) {
+			end = len(authors)
+		}
+		cfg.Do(Relay{Read: true}, func(ctx context.Context, relay *nostr.Relay) bool {
+			evs, err := relay.QuerySync(ctx, nostr.Filter{
+				Kinds:   []int{nostr.KindRelayListMetadata},
+				Authors: authors[i:end],
+			})
+			if err != nil {
+				return true
+			}
+			mu.Lock()
+			for _, ev := range evs {
+				cfg.cacheEvent(ev)
+				if old, ok := latest[ev.PubKey]; !ok || ev.CreatedAt > old.CreatedAt {
+					latest[ev.PubKey] = ev
+				}
+			}
+			mu.Unlock()
+			return true
+		})
+	}
+
+	result := map[string]map[string]Relay{}
+	for pub, ev := range latest {
+		result[pub] = ParseRelayList(ev)
+	}
+	return result
+}
+
+// outboxEvents queries each author on their own write relays and the
+// authors without relay list on the configured read relays.
+func (cfg *Config) outboxEvents(filter nostr.Filter, m *sync.Map) {
+	lists := cfg.GetRelayLists(filter.Authors)
+
+	// prefer relays shared by many authors to keep connections few
+	popularity := map[string]int{}
+	for _, rm := range lists {
+		for k, v := range rm {
+			if v.Write {
+				popularity[k]++
+			}
+		}
+	}
+
+	groups := map[string][]string{}
+	var fallback []string
+	for _, pub := range filter.Authors {
+		var urls []string
+		for k, v := range lists[pub] {
+			if v.Write {
+				urls = append(urls, k)
+			}
+		}
+		if len(urls) == 0 {
+			fallback = append(fallback, pub)
+			continue
+		}
+		sort.Slice(urls, func(i, j int) bool {
+			if popularity[urls[i]] != popularity[urls[j]] {
+				return popularity[urls[i]] > popularity[urls[j]]
+			}
+			return urls[i] < urls[j]
+		})
+		if len(urls) > outboxRelaysPerAuthor {
+			urls = urls[:outboxRelaysPerAuthor]
+		}
+		for _, u := range urls {
+			groups[u] = append(groups[u], pub)
+		}
+	}
+	if cfg.Verbose {
+		fmt.Printf("outbox: %d relays, %d authors without relay list\n", len(groups), len(fallback))
+	}
+
+	var wg sync.WaitGroup
+	if len(fallback) > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f := filter
+			f.Authors = fallback
+			cfg.relayEvents(f, m)
+		}()
+	}
+
+	sem := make(chan struct{}, outboxMaxConns)
+	for u, authors := range groups {
+		wg.Add(1)
+		go func(u string, authors []string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			ctx := context.Background()
+			relay, err := nostr.RelayConnect(ctx, u)
+			if err != nil {
+				if cfg.Verbose {
+					fmt.Fprintln(os.Stderr, err)
+				}
+				return
+			}
+			defer relay.Close()
+
+			f := filter
+			f.Authors = authors
+			evs, err := relay.QuerySync(ctx, f)
+			if err != nil {
+				return
+			}
+			for _, ev := range evs {
+				cfg.storeEvent(m, ev)
+			}
+		}(u, authors)
+	}
+	wg.Wait()
+}
@@ -1,8 +1,59 @@
 package domain
 
+import (
+	"sort"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
 // Relay is
 type Relay struct {
 	Read   bool `json:"read"`
 	Write  bool `json:"write"`
 	Search bool `json:"search"`
 }
+
+// ParseRelayList is
+func ParseRelayList(ev *nostr.Event) map[string]Relay {
+	rm := map[string]Relay{}
+	for _, tag := range ev.Tags {
+		if len(tag) < 2 || tag[0] != "r" || !nostr.IsValidRelayURL(tag[1]) {
+			continue
+		}
+		u := nostr.NormalizeURL(tag[1])
+		r := rm[u]
+		if len(tag) == 2 || tag[2] == "" {
+			r.Read = true
+			r.Write = true
+		} else if tag[2] == "read" {
+			r.Read = true
+		} else if tag[2] == "write" {
+			r.Write = true
+		}
+		rm[u] = r
+	}
+	return rm
+}
+
+// RelayListTags is
+func RelayListTags(relays map[string]Relay) nostr.Tags {
+	keys := []string{}
+	for k := range relays {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	tags := nostr.Tags{}
+	for _, k := range keys {
+		v := relays[k]
+		switch {
+		case v.Read && v.Write:
+			tags = append(tags, nostr.Tag{"r", k})
+		case v.Read:
+			tags = append(tags, nostr.Tag{"r", k, "read"})
+		case v.Write:
+			tags = append(tags, nostr.Tag{"r", k, "write"})
+		}
+	}
+	return tags
+}
@@ -0,0 +1,214 @@
+package domain
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip19"
+)
+
+var referenceRe = regexp.MustCompile(`nostr:(?:npub|nprofile|note|nevent|naddr)1[02-9ac-hj-np-z]+|#\[\d+\]`)
+
+// reference returns the public key, nostr.EventPointer or nostr.EntityPointer
+// which the NIP-27 reference or legacy #[n] mention in the event points to.
+func reference(ev *nostr.Event, s string) any {
+	if strings.HasPrefix(s, "#[") {
+		i, err := strconv.Atoi(s[2 : len(s)-1])
+		if err != nil || i >= len(ev.Tags) || len(ev.Tags[i]) < 2 {
+			return nil
+		}
+		switch tag := ev.Tags[i]; tag[0] {
+		case "p":
+			if nostr.IsValidPublicKey(tag[1]) {
+				return tag[1]
+			}
+		case "e":
+			if nostr.IsValid32ByteHex(tag[1]) {
+				return nostr.EventPointer{ID: tag[1]}
+			}
+		}
+		return nil
+	}
+
+	prefix, value, err := DecodeEntity(s)
+	if err != nil {
+		return nil
+	}
+	switch prefix {
+	case "npub":
+		return value.(string)
+	case "nprofile":
+		return value.(nostr.ProfilePointer).PublicKey
+	case "note":
+		return nostr.EventPointer{ID: value.(string)}
+	case "nevent":
+		return value.(nostr.EventPointer)
+	case "naddr":
+		return value.(nostr.EntityPointer)
+	}
+	return nil
+}
+
+func entityKey(ep nostr.EntityPointer) string {
+	return strconv.Itoa(ep.Kind) + ":" + ep.PublicKey + ":" + ep.Identifier
+}
+
+// fetchReferences fetches profiles and events referred in the events at once,
+// so that RenderContent does not query relays for each of them.
+func (cfg *Config) fetchReferences(evs []*nostr.Event, followsMap map[string]Profile) {
+	if cfg.profiles == nil {
+		cfg.profiles = map[string]*Profile{}
+		cfg.quoted = map[string]*nostr.Event{}
+	}
+
+	pubs := map[string]struct{}{}
+	var ids []string
+	var entities []nostr.EntityPointer
+	for _, ev := range evs {
+		for _, s := range referenceRe.FindAllString(ev.Content, -1) {
+			switch ref := reference(ev, s).(type) {
+			case string:
+				pubs[ref] = struct{}{}
+			case nostr.EventPointer:
+				if _, ok := cfg.quoted[ref.ID]; !ok {
+					cfg.quoted[ref.ID] = nil
+					ids = append(ids, ref.ID)
+				}
+			case nostr.EntityPointer:
+				key := entityKey(ref)
+				if _, ok := cfg.quoted[key]; ok {
+					continue
+				}
+				cfg.quoted[key] = nil
+				entities = append(entities, ref)
+			}
+		}
+	}
+	if len(ids) > 0 {
+		for _, ev := range cfg.Events(nostr.Filter{IDs: ids}) {
+			cfg.quoted[ev.ID] = ev
+		}
+	}
+	if len(entities) > 0 {
+		// one filter for all of them, events of other combinations are ignored
+		filter := nostr.Filter{Tags: nostr.TagMap{}}
+		for _, ep := range entities {
+			filter.Kinds = append(filter.Kinds, ep.Kind)
+			filter.Authors = append(filter.Authors, ep.PublicKey)
+			filter.Tags["d"] = append(filter.Tags["d"], ep.Identifier)
+		}
+		for _, ev := range cfg.Events(filter) {
+			key := entityKey(nostr.EntityPointer{Kind: ev.Kind, PublicKey: ev.PubKey, Identifier: ev.Tags.GetD()})
+			if _, ok := cfg.quoted[key]; ok {
+				cfg.quoted[key] = ev
+			}
+		}
+	}
+	for _, ev := range cfg.quoted {
+		if ev == nil {
+			continue
+		}
+		pubs[ev.PubKey] = struct{}{}
+		for _, s := range referenceRe.FindAllString(ev.Content, -1) {
+			if pub, ok := reference(ev, s).(string); ok {
+				pubs[pub] = struct{}{}
+			}
+		}
+	}
+
+	var authors []string
+	for pub := range pubs {
+		if _, ok := followsMap[pub]; ok {
+			continue
+		}
+		if _, ok := cfg.profiles[pub]; ok {
+			continue
+		}
+		cfg.profiles[pub] = nil
+		authors = append(authors, pub)
+	}
+	if len(authors) > 0 {
+		for _, ev := range cfg.Events(nostr.Filter{Kinds: []int{nostr.KindProfileMetadata}, Authors: authors}) {
+			var profile Profile
+			if json.Unmarshal([]byte(ev.Content), &profile) == nil {
+				cfg.profiles[ev.PubKey] = &profile
+			}
+		}
+	}
+}
+
+// profileName returns the name to display for the public key.
+func (cfg *Config) profileName(pub string, followsMap map[string]Profile) string {
+	profile, ok := followsMap[pub]
+	if !ok && cfg.profiles[pub] != nil {
+		profile, ok = *cfg.profiles[pub], true
+	}
+	if ok {
+		if profile.Name != "" {
+			return profile.Name
+		}
+		if profile.DisplayName != "" {
+			return profile.DisplayName
+		}
+	}
+	if npub, err := nip19.EncodePublicKey(pub); err == nil {
+		return npub
+	}
+	return pub
+}
+
+// RenderContent returns the content of the event with references to profiles
+// replaced by @name, and referred events quoted in following lines.
+func (cfg *Config) RenderContent(ev *nostr.Event, followsMap map[string]Profile) string {
+	if cfg.RawContent {
+		return ev.Content
+	}
+	cfg.fetchReferences([]*nostr.Event{ev}, followsMap)
+	return cfg.renderContent(ev, followsMap, true)
+}
+
+func (cfg *Config) renderContent(ev *nostr.Event, followsMap map[string]Profile, quote bool) string {
+	var buf strings.Builder
+	last := 0
+	for _, m := range referenceRe.FindAllStringIndex(ev.Content, -1) {
+		buf.WriteString(ev.Content[last:m[0]])
+		last = m[1]
+		s := ev.Content[m[0]:m[1]]
+
+		var quoted *nostr.Event
+		switch ref := reference(ev, s).(type) {
+		case string:
+			buf.WriteString("@" + cfg.profileName(ref, followsMap))
+			continue
+		case nostr.EventPointer:
+			quoted = cfg.quoted[ref.ID]
+		case nostr.EntityPointer:
+			quoted = cfg.quoted[entityKey(ref)]
+		}
+		// nested quotes are not expanded
+		if quoted == nil || !quote {
+			buf.WriteString(s)
+			continue
+		}
+		if m[0] > 0 && ev.Content[m[0]-1] != '\n' {
+			buf.WriteString("\n")
+		}
+		buf.WriteString("> " + cfg.profileName(quoted.PubKey, followsMap) + ": ")
+		if note, err := nip19.EncodeNote(quoted.ID); err == nil {
+			buf.WriteString(note)
+		} else {
+			buf.WriteString(quoted.ID)
+		}
+		for _, line := range strings.Split(cfg.renderContent(quoted, followsMap, false), "\n") {
+			buf.WriteString("\n> " + line)
+		}
+		if last < len(ev.Content) && ev.Content[last] != '\n' {
+			buf.WriteString("\n")
+		}
+	}
+	buf.WriteString(ev.Content[last:])
+	return buf.String()
+}
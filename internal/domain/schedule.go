@@ -0,0 +1,117 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// MaxScheduleAttempts is how many times a scheduled event is tried before
+// giving up.
+const MaxScheduleAttempts = 10
+
+// Scheduled is
+type Scheduled struct {
+	Event    *nostr.Event    `json:"event"`
+	Attempts int             `json:"attempts,omitempty"`
+	NextTry  nostr.Timestamp `json:"next-try,omitempty"`
+	Error    string          `json:"error,omitempty"`
+}
+
+// Due returns whether the event should be published at the time.
+func (s *Scheduled) Due(now nostr.Timestamp) bool {
+	return !s.GaveUp() && s.Event.CreatedAt <= now && s.NextTry <= now
+}
+
+// GaveUp returns whether the event failed too many times to be tried again.
+func (s *Scheduled) GaveUp() bool {
+	return s.Attempts >= MaxScheduleAttempts
+}
+
+// Failed records the error and delays the next try. The delay is doubled
+// for each attempt up to an hour.
+func (s *Scheduled) Failed(err error) {
+	s.Attempts++
+	s.Error = err.Error()
+	delay := time.Minute << min(s.Attempts-1, 6)
+	s.NextTry = nostr.Timestamp(time.Now().Add(min(delay, time.Hour)).Unix())
+}
+
+func (cfg *Config) scheduleFile(id string) (string, error) {
+	if !nostr.IsValid32ByteHex(id) {
+		return "", fmt.Errorf("invalid event id '%s'", id)
+	}
+	dir, err := cfg.profileDir("schedule")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, id+".json"), nil
+}
+
+// Schedules returns the queued events, earlier first.
+func (cfg *Config) Schedules() ([]*Scheduled, error) {
+	dir, err := cfg.profileDir("schedule")
+	if err != nil {
+		return nil, err
+	}
+	names, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	var schedules []*Scheduled
+	for _, name := range names {
+		b, err := os.ReadFile(name)
+		if err != nil {
+			// published or canceled by another process
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		var s Scheduled
+		if err := json.Unmarshal(b, &s); err != nil || s.Event == nil {
+			return nil, fmt.Errorf("broken schedule '%s'", filepath.Base(name))
+		}
+		schedules = append(schedules, &s)
+	}
+	sort.Slice(schedules, func(i, j int) bool {
+		return schedules[i].Event.CreatedAt < schedules[j].Event.CreatedAt
+	})
+	return schedules, nil
+}
+
+// SaveSchedule puts the signed event into the queue.
+func (cfg *Config) SaveSchedule(s *Scheduled) error {
+	fp, err := cfg.scheduleFile(s.Event.ID)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(fp), 0700); err != nil {
+		return err
+	}
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(fp, b, 0600)
+}
+
+// RemoveSchedule is
+func (cfg *Config) RemoveSchedule(id string) error {
+	fp, err := cfg.scheduleFile(id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(fp); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("scheduled event '%s' not found", id)
+		}
+		return err
+	}
+	return nil
+}
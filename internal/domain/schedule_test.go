@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nbd-wtf/go-nostr"
+)
+
+func TestScheduledFailed(t *testing.T) {
+	s := &Scheduled{Event: &nostr.Event{CreatedAt: nostr.Now() - 60}}
+	later := nostr.Timestamp(time.Now().Add(2 * time.Hour).Unix())
+	for i := 1; i < MaxScheduleAttempts; i++ {
+		s.Failed(errors.New("relay is down"))
+		if s.GaveUp() {
+			t.Fatalf("want retry after %d attempts", i)
+		}
+		if s.Due(nostr.Now()) {
+			t.Fatalf("want delay after %d attempts", i)
+		}
+		// the delay is at most an hour
+		if !s.Due(later) {
+			t.Fatalf("want due after %d attempts", i)
+		}
+	}
+	s.Failed(errors.New("relay is down"))
+	if !s.GaveUp() {
+		t.Fatalf("want gave up after %d attempts", s.Attempts)
+	}
+	if s.Due(later) {
+		t.Fatal("want no more tries")
+	}
+	if s.Error != "relay is down" {
+		t.Fatalf("want the last error, but got %q", s.Error)
+	}
+}
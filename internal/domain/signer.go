@@ -0,0 +1,154 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nbd-wtf/go-nostr"
+	"github.com/nbd-wtf/go-nostr/nip04"
+	"github.com/nbd-wtf/go-nostr/nip19"
+	"github.com/nbd-wtf/go-nostr/nip44"
+)
+
+// Signer signs events and encrypts contents on behalf of the user.
+type Signer interface {
+	GetPublicKey(ctx context.Context) (string, error)
+	SignEvent(ctx context.Context, ev *nostr.Event) error
+	Nip04Encrypt(ctx context.Context, pub string, plaintext string) (string, error)
+	Nip04Decrypt(ctx context.Context, pub string, ciphertext string) (string, error)
+	Nip44Encrypt(ctx context.Context, pub string, plaintext string) (string, error)
+	Nip44Decrypt(ctx context.Context, pub string, ciphertext string) (string, error)
+}
+
+// KeySigner is a Signer holding the private key locally.
+type KeySigner struct {
+	sk  string
+	pub string
+}
+
+// NewKeySigner returns a KeySigner for nsec or hex private key.
+func NewKeySigner(key string) (*KeySigner, error) {
+	sk := key
+	if strings.HasPrefix(key, "nsec") {
+		_, s, err := nip19.Decode(key)
+		if err != nil {
+			return nil, err
+		}
+		sk = s.(string)
+	}
+	pub, err := nostr.GetPublicKey(sk)
+	if err != nil {
+		return nil, err
+	}
+	return &KeySigner{sk: sk, pub: pub}, nil
+}
+
+// GetPublicKey is
+func (s *KeySigner) GetPublicKey(ctx context.Context) (string, error) {
+	return s.pub, nil
+}
+
+// SignEvent is
+func (s *KeySigner) SignEvent(ctx context.Context, ev *nostr.Event) error {
+	return ev.Sign(s.sk)
+}
+
+// Nip04Encrypt is
+func (s *KeySigner) Nip04Encrypt(ctx context.Context, pub string, plaintext string) (string, error) {
+	ss, err := nip04.ComputeSharedSecret(pub, s.sk)
+	if err != nil {
+		return "", err
+	}
+	return nip04.Encrypt(plaintext, ss)
+}
+
+// Nip04Decrypt is
+func (s *KeySigner) Nip04Decrypt(ctx context.Context, pub string, ciphertext string) (string, error) {
+	ss, err := nip04.ComputeSharedSecret(pub, s.sk)
+	if err != nil {
+		return "", err
+	}
+	return nip04.Decrypt(ciphertext, ss)
+}
+
+// Nip44Encrypt is
+func (s *KeySigner) Nip44Encrypt(ctx context.Context, pub string, plaintext string) (string, error) {
+	ck, err := nip44.GenerateConversationKey(pub, s.sk)
+	if err != nil {
+		return "", err
+	}
+	return nip44.Encrypt(plaintext, ck)
+}
+
+// Nip44Decrypt is
+func (s *KeySigner) Nip44Decrypt(ctx context.Context, pub string, ciphertext string) (string, error) {
+	ck, err := nip44.GenerateConversationKey(pub, s.sk)
+	if err != nil {
+		return "", err
+	}
+	return nip44.Decrypt(ciphertext, ck)
+}
+
+// saveBunkerClientKey stores the client key so that the bunker does not have to
+// authorize a new client on each run. With --relays, the config is not saved,
+// so only the key is written into the config file.
+func (cfg *Config) saveBunkerClientKey() error {
+	if !cfg.TempRelay {
+		return cfg.Save(cfg.profile)
+	}
+	saved, err := LoadConfig(cfg.profile)
+	if err != nil {
+		return err
+	}
+	saved.BunkerClientKey = cfg.BunkerClientKey
+	return saved.Save(cfg.profile)
+}
+
+// Signer returns the signer configured with bunker or privatekey.
+func (cfg *Config) Signer() (Signer, error) {
+	// it is called from goroutines for relays, connect or ask the passphrase once
+	cfg.signerMu.Lock()
+	defer cfg.signerMu.Unlock()
+
+	if cfg.signer != nil {
+		return cfg.signer, nil
+	}
+	if cfg.Bunker != "" {
+		if cfg.BunkerClientKey == "" {
+			cfg.BunkerClientKey = nostr.GeneratePrivateKey()
+			if err := cfg.saveBunkerClientKey(); err != nil {
+				return nil, err
+			}
+		}
+		bunker, err := ConnectBunker(context.Background(), cfg.BunkerClientKey, cfg.Bunker)
+		if err != nil {
+			return nil, fmt.Errorf("cannot connect bunker: %w", err)
+		}
+		cfg.signer = bunker
+		return cfg.signer, nil
+	}
+	if cfg.PrivateKey == "" {
+		return nil, errors.New("privatekey or bunker is not configured")
+	}
+	sk, err := cfg.DecryptKey()
+	if err != nil {
+		return nil, err
+	}
+	signer, err := NewKeySigner(sk)
+	if err != nil {
+		return nil, err
+	}
+	cfg.signer = signer
+	return cfg.signer, nil
+}
+
+// PublicKey returns the public key of the signer.
+func (cfg *Config) PublicKey() (string, error) {
+	signer, err := cfg.Signer()
+	if err != nil {
+		return "", err
+	}
+	return signer.GetPublicKey(context.Background())
+}
@@ -0,0 +1,114 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/nbd-wtf/go-nostr"
+)
+
+// Stats is
+type Stats struct {
+	Reactions map[string]int `json:"reactions,omitempty"`
+	Reposts   int            `json:"reposts"`
+	Zaps      int            `json:"zaps"`
+	ZapSats   int64          `json:"zap_sats"`
+}
+
+// EventStats returns reaction, repost and zap counts of the events.
+func (cfg *Config) EventStats(evs []*nostr.Event) map[string]*Stats {
+	stats := map[string]*Stats{}
+	var ids []string
+	for _, ev := range evs {
+		if _, ok := stats[ev.ID]; !ok {
+			stats[ev.ID] = &Stats{}
+			ids = append(ids, ev.ID)
+		}
+	}
+
+	for i := 0; i < len(ids); i += 200 {
+		end := i + 200
+		if end > len(ids) {
+			end = len(ids)
+		}
+		filter := nostr.Filter{
+			Kinds: []int{nostr.KindReaction, nostr.KindRepost, KindGenericRepost, nostr.KindZap},
+			Tags:  nostr.TagMap{"e": ids[i:end]},
+		}
+		for _, ev := range cfg.Events(filter) {
+			// the last e tag is the target of reactions
+			var s *Stats
+			for _, tag := range ev.Tags {
+				if len(tag) >= 2 && tag[0] == "e" {
+					if v, ok := stats[tag[1]]; ok {
+						s = v
+					}
+				}
+			}
+			if s == nil {
+				continue
+			}
+			switch ev.Kind {
+			case nostr.KindReaction:
+				content := ev.Content
+				if content == "" {
+					content = "+"
+				}
+				if s.Reactions == nil {
+					s.Reactions = map[string]int{}
+				}
+				s.Reactions[content]++
+			case nostr.KindRepost, KindGenericRepost:
+				s.Reposts++
+			case nostr.KindZap:
+				tag := ev.Tags.GetFirst([]string{"bolt11", ""})
+				if tag == nil {
+					continue
+				}
+				msats, err := Bolt11Amount((*tag)[1])
+				if err != nil {
+					continue
+				}
+				s.Zaps++
+				s.ZapSats += msats / 1000
+			}
+		}
+	}
+	return stats
+}
+
+// PrintStats is
+func (cfg *Config) PrintStats(s *Stats, indent string) {
+	var parts []string
+	if len(s.Reactions) > 0 {
+		var keys []string
+		for k := range s.Reactions {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if s.Reactions[keys[i]] != s.Reactions[keys[j]] {
+				return s.Reactions[keys[i]] > s.Reactions[keys[j]]
+			}
+			return keys[i] < keys[j]
+		})
+		var reactions []string
+		for _, k := range keys {
+			reactions = append(reactions, fmt.Sprintf("%s %d", k, s.Reactions[k]))
+		}
+		parts = append(parts, strings.Join(reactions, " "))
+	}
+	if s.Reposts > 0 {
+		parts = append(parts, fmt.Sprintf("%d reposts", s.Reposts))
+	}
+	if s.Zaps > 0 {
+		parts = append(parts, fmt.Sprintf("%d sats by %d zaps", s.ZapSats, s.Zaps))
+	}
+	if len(parts) == 0 {
+		return
+	}
+	color.Set(color.FgHiBlack)
+	fmt.Println(indent + strings.Join(parts, " | "))
+	color.Set(color.Reset)
+}
@@ -1,5 +1,13 @@
 package domain
 
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
 // Lnurlp is
 type Lnurlp struct {
 	Callback       string `json:"callback"`
@@ -37,3 +45,44 @@ type PayResponse struct {
 		Preimage string `json:"preimage"`
 	} `json:"result"`
 }
+
+// Bolt11Amount returns the amount of the invoice in millisatoshi.
+func Bolt11Amount(invoice string) (int64, error) {
+	invoice = strings.TrimPrefix(strings.ToLower(invoice), "lightning:")
+	pos := strings.LastIndexByte(invoice, '1')
+	if !strings.HasPrefix(invoice, "ln") || pos < 0 {
+		return 0, errors.New("invalid invoice")
+	}
+	// ln + currency + amount + multiplier
+	hrp := invoice[2:pos]
+	i := strings.IndexFunc(hrp, unicode.IsDigit)
+	if i < 0 {
+		return 0, errors.New("invoice has no amount")
+	}
+	amount := hrp[i:]
+	// millisatoshi per unit, 1 pico-bitcoin is 0.1 millisatoshi
+	mul, div := int64(100000000000), int64(1)
+	digits := amount
+	switch amount[len(amount)-1] {
+	case 'm':
+		mul = 100000000
+	case 'u':
+		mul = 100000
+	case 'n':
+		mul = 100
+	case 'p':
+		mul, div = 1, 10
+	}
+	if mul != 100000000000 {
+		digits = amount[:len(amount)-1]
+	}
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid amount '%s'", amount)
+	}
+	// BOLT 11 does not allow sub-millisatoshi amounts
+	if n%div != 0 {
+		return 0, fmt.Errorf("invalid amount '%s'", amount)
+	}
+	return n * mul / div, nil
+}
@@ -0,0 +1,38 @@
+package domain
+
+import "testing"
+
+func TestBolt11Amount(t *testing.T) {
+	tests := []struct {
+		invoice string
+		want    int64
+		wantErr bool
+	}{
+		{invoice: "lnbc20m1pvjluezpp5qqqsyqcyq5", want: 2000000000},
+		{invoice: "lnbc2500u1pvjluezpp5qqqsyqcyq5", want: 250000000},
+		{invoice: "lnbc10n1pvjluezpp5qqqsyqcyq5", want: 1000},
+		{invoice: "lnbc10p1pvjluezpp5qqqsyqcyq5", want: 1},
+		{invoice: "lnbc11pvjluezpp5qqqsyqcyq5", want: 100000000000},
+		{invoice: "LIGHTNING:LNBC2500U1PVJLUEZPP5QQQSYQCYQ5", want: 250000000},
+		{invoice: "lntb20m1pvjluezpp5qqqsyqcyq5", want: 2000000000},
+		{invoice: "lnbc1pvjluezpp5qqqsyqcyq5", wantErr: true},
+		{invoice: "lnbc15p1pvjluezpp5qqqsyqcyq5", wantErr: true},
+		{invoice: "lnbc20x1pvjluezpp5qqqsyqcyq5", wantErr: true},
+		{invoice: "lnbc20mm1pvjluezpp5qqqsyqcyq5", wantErr: true},
+		{invoice: "bc20m1pvjluezpp5qqqsyqcyq5", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := Bolt11Amount(tt.invoice)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: want error, but got %d", tt.invoice, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.invoice, err)
+		} else if got != tt.want {
+			t.Errorf("%s: want %d, but got %d", tt.invoice, tt.want, got)
+		}
+	}
+}
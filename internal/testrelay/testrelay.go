@@ -0,0 +1,123 @@
+// Package testrelay provides a relay in memory for tests.
+package testrelay
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/gobwas/ws"
+	"github.com/gobwas/ws/wsutil"
+	"github.com/nbd-wtf/go-nostr"
+)
+
+type conn struct {
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func (c *conn) send(env nostr.Envelope) {
+	b, err := env.MarshalJSON()
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	wsutil.WriteServerText(c.conn, b)
+}
+
+// Relay is a relay in memory which stores every valid event and serves
+// subscriptions.
+type Relay struct {
+	URL string
+
+	srv    *httptest.Server
+	mu     sync.Mutex
+	events []*nostr.Event
+	subs   map[*conn]map[string]nostr.Filters
+}
+
+// New starts a relay. The caller should call Close when finished.
+func New() *Relay {
+	r := &Relay{subs: map[*conn]map[string]nostr.Filters{}}
+	r.srv = httptest.NewServer(http.HandlerFunc(r.serve))
+	r.URL = "ws" + strings.TrimPrefix(r.srv.URL, "http")
+	return r
+}
+
+// Close shuts down the relay.
+func (r *Relay) Close() {
+	r.srv.Close()
+}
+
+// Events returns the stored events which match the filter.
+func (r *Relay) Events(filter nostr.Filter) []*nostr.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var evs []*nostr.Event
+	for _, ev := range r.events {
+		if filter.Matches(ev) {
+			evs = append(evs, ev)
+		}
+	}
+	return evs
+}
+
+func (r *Relay) serve(w http.ResponseWriter, req *http.Request) {
+	wc, _, _, err := ws.UpgradeHTTP(req, w)
+	if err != nil {
+		return
+	}
+	c := &conn{conn: wc}
+	defer func() {
+		r.mu.Lock()
+		delete(r.subs, c)
+		r.mu.Unlock()
+		wc.Close()
+	}()
+	for {
+		msg, err := wsutil.ReadClientText(wc)
+		if err != nil {
+			return
+		}
+		switch env := nostr.ParseMessage(msg).(type) {
+		case *nostr.EventEnvelope:
+			ok, _ := env.Event.CheckSignature()
+			c.send(&nostr.OKEnvelope{EventID: env.Event.ID, OK: ok})
+			if !ok {
+				continue
+			}
+			r.mu.Lock()
+			r.events = append(r.events, &env.Event)
+			for oc, subs := range r.subs {
+				for id, filters := range subs {
+					if filters.Match(&env.Event) {
+						id := id
+						oc.send(&nostr.EventEnvelope{SubscriptionID: &id, Event: env.Event})
+					}
+				}
+			}
+			r.mu.Unlock()
+		case *nostr.ReqEnvelope:
+			r.mu.Lock()
+			for _, ev := range r.events {
+				if env.Filters.Match(ev) {
+					c.send(&nostr.EventEnvelope{SubscriptionID: &env.SubscriptionID, Event: *ev})
+				}
+			}
+			if r.subs[c] == nil {
+				r.subs[c] = map[string]nostr.Filters{}
+			}
+			r.subs[c][env.SubscriptionID] = env.Filters
+			r.mu.Unlock()
+			eose := nostr.EOSEEnvelope(env.SubscriptionID)
+			c.send(&eose)
+		case *nostr.CloseEnvelope:
+			r.mu.Lock()
+			delete(r.subs[c], string(*env))
+			r.mu.Unlock()
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/urfave/cli/v2"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/nbd-wtf/go-nostr"
 )
@@ -19,8 +20,32 @@ func main() {
 			&cli.StringFlag{Name: "a", Usage: "profile name"},
 			&cli.StringFlag{Name: "relays", Usage: "relays"},
 			&cli.BoolFlag{Name: "V", Usage: "verbose"},
+			&cli.BoolFlag{Name: "outbox", Usage: "fetch notes from authors' write relays"},
+			&cli.BoolFlag{Name: "offline", Usage: "serve events from local cache only"},
+			&cli.BoolFlag{Name: "since-cache", Usage: "fetch only events newer than cached ones"},
+			&cli.BoolFlag{Name: "raw-content", Usage: "show content without resolving references"},
+			&cli.IntFlag{Name: "passphrase-fd", Value: -1, Usage: "read passphrase of ncryptsec from file descriptor"},
 		},
 		Commands: []*cli.Command{
+			{
+				Name: "init",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "import", Usage: "import nsec, hex key or mnemonic words (- for stdin)"},
+					&cli.StringSliceFlag{Name: "relay", Usage: "relays"},
+					&cli.BoolFlag{Name: "encrypt", Usage: "encrypt private key with passphrase (NIP-49)"},
+					&cli.BoolFlag{Name: "force", Usage: "overwrite existing config"},
+					&cli.BoolFlag{Name: "publish", Usage: "publish profile and relay list"},
+					&cli.StringFlag{Name: "name", Usage: "name"},
+					&cli.StringFlag{Name: "display-name", Usage: "display name"},
+					&cli.StringFlag{Name: "about", Usage: "about"},
+					&cli.StringFlag{Name: "picture", Usage: "picture URL"},
+				},
+				Usage:     "create config",
+				UsageText: "algia init [profile name]",
+				HelpName:  "init",
+				ArgsUsage: "[profile name]",
+				Action:    cmd.DoInit,
+			},
 			{
 				Name:    "timeline",
 				Aliases: []string{"tl"},
@@ -30,7 +55,12 @@ func main() {
 					&cli.IntFlag{Name: "n", Value: 30, Usage: "number of items"},
 					&cli.BoolFlag{Name: "json", Usage: "output JSON"},
 					&cli.BoolFlag{Name: "extra", Usage: "extra JSON"},
+					&cli.BoolFlag{Name: "stats", Usage: "show reaction, repost and zap counts"},
 					&cli.BoolFlag{Name: "article", Usage: "show articles"},
+					&cli.StringFlag{Name: "since", Usage: "since (unix time, RFC3339, date or duration like 2h)"},
+					&cli.StringFlag{Name: "until", Usage: "until (unix time, RFC3339, date or duration like 2h)"},
+					&cli.BoolFlag{Name: "page", Usage: "print cursor for the next page"},
+					&cli.StringFlag{Name: "cursor", Usage: "continue from the cursor printed with --page"},
 				},
 				Action: cmd.DoTimeline,
 			},
@@ -46,18 +76,33 @@ func main() {
 				},
 				Action: cmd.DoStream,
 			},
+			{
+				Name:    "notifications",
+				Aliases: []string{"nt"},
+				Usage:   "show notifications",
+				Flags: []cli.Flag{
+					&cli.IntFlag{Name: "n", Value: 30, Usage: "number of items"},
+					&cli.BoolFlag{Name: "unread", Usage: "show only items newer than last seen"},
+					&cli.StringFlag{Name: "since", Usage: "since (unix time, RFC3339, date or duration like 2h)"},
+					&cli.StringFlag{Name: "until", Usage: "until (unix time, RFC3339, date or duration like 2h)"},
+					&cli.BoolFlag{Name: "json", Usage: "output JSON"},
+				},
+				Action: cmd.DoNotifications,
+			},
 			{
 				Name:    "post",
 				Aliases: []string{"n"},
 				Flags: []cli.Flag{
 					&cli.StringSliceFlag{Name: "u", Usage: "users"},
 					&cli.BoolFlag{Name: "stdin"},
+					&cli.BoolFlag{Name: "edit", Usage: "compose in $EDITOR"},
 					&cli.StringFlag{Name: "sensitive"},
 					&cli.StringSliceFlag{Name: "emoji"},
 					&cli.StringFlag{Name: "geohash"},
 					&cli.StringFlag{Name: "article-name"},
 					&cli.StringFlag{Name: "article-title"},
 					&cli.StringFlag{Name: "article-summary"},
+					&cli.StringFlag{Name: "at", Usage: "schedule the note at the time"},
 				},
 				Usage:     "post new note",
 				UsageText: "algia post [note text]",
@@ -69,7 +114,9 @@ func main() {
 				Name:    "reply",
 				Aliases: []string{"r"},
 				Flags: []cli.Flag{
+					&cli.StringSliceFlag{Name: "u", Usage: "users"},
 					&cli.BoolFlag{Name: "stdin"},
+					&cli.BoolFlag{Name: "edit", Usage: "compose in $EDITOR"},
 					&cli.StringFlag{Name: "id", Required: true},
 					&cli.BoolFlag{Name: "quote"},
 					&cli.StringFlag{Name: "sensitive"},
@@ -82,6 +129,23 @@ func main() {
 				ArgsUsage: "[note text]",
 				Action:    cmd.DoReply,
 			},
+			{
+				Name: "quote",
+				Flags: []cli.Flag{
+					&cli.StringSliceFlag{Name: "u", Usage: "users"},
+					&cli.BoolFlag{Name: "stdin"},
+					&cli.BoolFlag{Name: "edit", Usage: "compose in $EDITOR"},
+					&cli.StringFlag{Name: "id", Required: true},
+					&cli.StringFlag{Name: "sensitive"},
+					&cli.StringSliceFlag{Name: "emoji"},
+					&cli.StringFlag{Name: "geohash"},
+				},
+				Usage:     "quote the note",
+				UsageText: "algia quote --id [id] [note text]",
+				HelpName:  "quote",
+				ArgsUsage: "[note text]",
+				Action:    cmd.DoQuote,
+			},
 			{
 				Name:    "repost",
 				Aliases: []string{"b"},
@@ -146,12 +210,66 @@ func main() {
 					&cli.IntFlag{Name: "n", Value: 30, Usage: "number of items"},
 					&cli.BoolFlag{Name: "json", Usage: "output JSON"},
 					&cli.BoolFlag{Name: "extra", Usage: "extra JSON"},
+					&cli.BoolFlag{Name: "stats", Usage: "show reaction, repost and zap counts"},
+					&cli.StringFlag{Name: "since", Usage: "since (unix time, RFC3339, date or duration like 2h)"},
+					&cli.StringFlag{Name: "until", Usage: "until (unix time, RFC3339, date or duration like 2h)"},
+					&cli.BoolFlag{Name: "page", Usage: "print cursor for the next page"},
+					&cli.StringFlag{Name: "cursor", Usage: "continue from the cursor printed with --page"},
 				},
 				Usage:     "search notes",
 				UsageText: "algia search [words]",
 				HelpName:  "search",
 				Action:    cmd.DoSearch,
 			},
+			{
+				Name: "thread",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "id", Required: true},
+					&cli.BoolFlag{Name: "json", Usage: "output JSON"},
+					&cli.BoolFlag{Name: "extra", Usage: "extra JSON"},
+					&cli.BoolFlag{Name: "stats", Usage: "show reaction, repost and zap counts"},
+				},
+				Usage:     "show the thread",
+				UsageText: "algia thread --id [id]",
+				HelpName:  "thread",
+				Action:    cmd.DoThread,
+			},
+			{
+				Name: "event",
+				Flags: []cli.Flag{
+					&cli.IntFlag{Name: "kind", Value: nostr.KindTextNote, Usage: "kind"},
+					&cli.StringSliceFlag{Name: "tag", Aliases: []string{"t"}, Usage: "tag (key=value or key=value1;value2)"},
+					&cli.StringFlag{Name: "content", Usage: "content"},
+					&cli.BoolFlag{Name: "stdin", Usage: "read event JSON from stdin"},
+					&cli.BoolFlag{Name: "sign-only", Usage: "print signed event without publishing"},
+				},
+				Usage:     "publish raw event",
+				UsageText: "algia event --kind [kind] --tag [key=value] --content [content]",
+				HelpName:  "event",
+				Action:    cmd.DoEvent,
+			},
+			{
+				Name: "req",
+				Flags: []cli.Flag{
+					&cli.StringSliceFlag{Name: "id", Usage: "event ids"},
+					&cli.StringSliceFlag{Name: "author", Usage: "authors"},
+					&cli.IntSliceFlag{Name: "kind", Usage: "kinds"},
+					&cli.StringSliceFlag{Name: "e", Usage: "#e tag"},
+					&cli.StringSliceFlag{Name: "p", Usage: "#p tag"},
+					&cli.StringSliceFlag{Name: "t", Usage: "#t tag"},
+					&cli.StringSliceFlag{Name: "tag", Usage: "tag filter (key=value or key=value1;value2)"},
+					&cli.StringFlag{Name: "since", Usage: "since (unix time, RFC3339, date or duration like 2h)"},
+					&cli.StringFlag{Name: "until", Usage: "until (unix time, RFC3339, date or duration like 2h)"},
+					&cli.IntFlag{Name: "limit", Usage: "limit"},
+					&cli.StringFlag{Name: "search", Usage: "search words (NIP-50)"},
+					&cli.StringSliceFlag{Name: "relay", Usage: "relays to query instead of configured ones"},
+					&cli.BoolFlag{Name: "stream", Usage: "keep subscription open"},
+				},
+				Usage:     "query events with filter",
+				UsageText: "algia req --kind [kind] --author [pubkey] --limit [n]",
+				HelpName:  "req",
+				Action:    cmd.DoReq,
+			},
 			{
 				Name: "broadcast",
 				Flags: []cli.Flag{
@@ -166,6 +284,7 @@ func main() {
 			{
 				Name: "dm-list",
 				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "legacy", Usage: "use NIP-04 direct messages"},
 					&cli.BoolFlag{Name: "json", Usage: "output JSON"},
 				},
 				Usage:     "show DM list",
@@ -176,6 +295,7 @@ func main() {
 			{
 				Name: "dm-timeline",
 				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "legacy", Usage: "use NIP-04 direct messages"},
 					&cli.StringFlag{Name: "u", Value: "", Usage: "DM user", Required: true},
 					&cli.BoolFlag{Name: "json", Usage: "output JSON"},
 					&cli.BoolFlag{Name: "extra", Usage: "extra JSON"},
@@ -188,8 +308,10 @@ func main() {
 			{
 				Name: "dm-post",
 				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "legacy", Usage: "use NIP-04 direct messages"},
 					&cli.StringFlag{Name: "u", Value: "", Usage: "DM user", Required: true},
 					&cli.BoolFlag{Name: "stdin"},
+					&cli.BoolFlag{Name: "edit", Usage: "compose in $EDITOR"},
 					&cli.StringFlag{Name: "sensitive"},
 				},
 				Usage:     "post new DM note",
@@ -198,10 +320,25 @@ func main() {
 				ArgsUsage: "[note text]",
 				Action:    cmd.DoDMPost,
 			},
+			{
+				Name: "dm-chat",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "u", Value: "", Usage: "DM user", Required: true},
+					&cli.IntFlag{Name: "n", Value: 20, Usage: "number of history items"},
+					&cli.BoolFlag{Name: "legacy", Usage: "use NIP-04 direct messages"},
+				},
+				Usage:     "chat with DM user",
+				UsageText: "algia dm-chat -u [user]",
+				HelpName:  "dm-chat",
+				Action:    cmd.DoDMChat,
+			},
 			{
 				Name: "bm-list",
 				Flags: []cli.Flag{
+					&cli.IntFlag{Name: "n", Value: 0, Usage: "number of items"},
 					&cli.BoolFlag{Name: "json", Usage: "output JSON"},
+					&cli.BoolFlag{Name: "extra", Usage: "extra JSON"},
+					&cli.BoolFlag{Name: "raw", Usage: "show bookmark entries"},
 				},
 				Usage:     "show bookmarks",
 				UsageText: "algia bm-list",
@@ -209,13 +346,141 @@ func main() {
 				Action:    cmd.DoBMList,
 			},
 			{
-				Name:      "bm-post",
+				Name: "bm-post",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "private", Usage: "private bookmark"},
+					&cli.BoolFlag{Name: "force", Usage: "publish even if the bookmark list is not found"},
+				},
 				Usage:     "post bookmark",
-				UsageText: "algia bm-post [note]",
+				UsageText: "algia bm-post [note|nevent|naddr|#hashtag|url]",
 				HelpName:  "bm-post",
-				ArgsUsage: "[note]",
+				ArgsUsage: "[note|nevent|naddr|#hashtag|url]",
 				Action:    cmd.DoBMPost,
 			},
+			{
+				Name: "bm-delete",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "force", Usage: "publish even if the bookmark list is not found"},
+				},
+				Usage:     "delete bookmark",
+				UsageText: "algia bm-delete [note|nevent|naddr|#hashtag|url]",
+				HelpName:  "bm-delete",
+				ArgsUsage: "[note|nevent|naddr|#hashtag|url]",
+				Action:    cmd.DoBMDelete,
+			},
+			{
+				Name:  "draft",
+				Usage: "manage drafts",
+				Subcommands: []*cli.Command{
+					{
+						Name: "save",
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "id", Usage: "draft id to overwrite"},
+							&cli.StringSliceFlag{Name: "u", Usage: "users"},
+							&cli.BoolFlag{Name: "stdin"},
+							&cli.BoolFlag{Name: "edit", Usage: "compose in $EDITOR"},
+							&cli.StringFlag{Name: "sensitive"},
+							&cli.StringSliceFlag{Name: "emoji"},
+							&cli.StringFlag{Name: "geohash"},
+							&cli.StringFlag{Name: "article-name"},
+							&cli.StringFlag{Name: "article-title"},
+							&cli.StringFlag{Name: "article-summary"},
+							&cli.BoolFlag{Name: "sync", Usage: "sync drafts with relays (NIP-37)"},
+						},
+						Usage:     "save new draft",
+						UsageText: "algia draft save [note text]",
+						HelpName:  "save",
+						ArgsUsage: "[note text]",
+						Action:    cmd.DoDraftSave,
+					},
+					{
+						Name: "list",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{Name: "json", Usage: "output JSON"},
+							&cli.BoolFlag{Name: "sync", Usage: "sync drafts with relays (NIP-37)"},
+						},
+						Usage:     "show drafts",
+						UsageText: "algia draft list",
+						HelpName:  "list",
+						Action:    cmd.DoDraftList,
+					},
+					{
+						Name: "edit",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{Name: "sync", Usage: "sync drafts with relays (NIP-37)"},
+						},
+						Usage:     "edit the draft in $EDITOR",
+						UsageText: "algia draft edit [id]",
+						HelpName:  "edit",
+						ArgsUsage: "[id]",
+						Action:    cmd.DoDraftEdit,
+					},
+					{
+						Name: "publish",
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "at", Usage: "schedule the note at the time"},
+							&cli.BoolFlag{Name: "sync", Usage: "sync drafts with relays (NIP-37)"},
+						},
+						Usage:     "post the draft",
+						UsageText: "algia draft publish [id]",
+						HelpName:  "publish",
+						ArgsUsage: "[id]",
+						Action:    cmd.DoDraftPublish,
+					},
+					{
+						Name: "rm",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{Name: "sync", Usage: "sync drafts with relays (NIP-37)"},
+						},
+						Usage:     "remove drafts",
+						UsageText: "algia draft rm [id...]",
+						HelpName:  "rm",
+						ArgsUsage: "[id...]",
+						Action:    cmd.DoDraftRemove,
+					},
+				},
+			},
+			{
+				Name:  "schedule",
+				Usage: "manage scheduled notes",
+				Subcommands: []*cli.Command{
+					{
+						Name: "run",
+						Flags: []cli.Flag{
+							&cli.DurationFlag{Name: "interval", Value: time.Minute, Usage: "interval to check the queue"},
+						},
+						Usage:     "publish scheduled notes when they are due",
+						UsageText: "algia schedule run",
+						HelpName:  "run",
+						Action:    cmd.DoScheduleRun,
+					},
+					{
+						Name:      "flush",
+						Usage:     "publish scheduled notes which are due",
+						UsageText: "algia schedule flush",
+						HelpName:  "flush",
+						Action:    cmd.DoScheduleFlush,
+					},
+					{
+						Name: "list",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{Name: "json", Usage: "output JSON"},
+						},
+						Usage:     "show scheduled notes",
+						UsageText: "algia schedule list",
+						HelpName:  "list",
+						Action:    cmd.DoScheduleList,
+					},
+					{
+						Name:      "cancel",
+						Usage:     "cancel scheduled notes",
+						UsageText: "algia schedule cancel [note...]",
+						HelpName:  "cancel",
+						ArgsUsage: "[note...]",
+						Action:    cmd.DoScheduleCancel,
+					},
+				},
+			},
 			{
 				Name: "profile",
 				Flags: []cli.Flag{
@@ -227,6 +492,123 @@ func main() {
 				HelpName:  "profile",
 				Action:    cmd.DoProfile,
 			},
+			{
+				Name: "profile-set",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "name", Usage: "name"},
+					&cli.StringFlag{Name: "display-name", Usage: "display name"},
+					&cli.StringFlag{Name: "about", Usage: "about"},
+					&cli.StringFlag{Name: "picture", Usage: "picture URL"},
+					&cli.StringFlag{Name: "banner", Usage: "banner URL"},
+					&cli.StringFlag{Name: "website", Usage: "website URL"},
+					&cli.StringFlag{Name: "nip05", Usage: "NIP-05 identifier"},
+					&cli.StringFlag{Name: "lud16", Usage: "lightning address"},
+					&cli.StringFlag{Name: "lud06", Usage: "LNURL"},
+					&cli.StringFlag{Name: "from-file", Usage: "replace profile with JSON file (- for stdin)"},
+					&cli.BoolFlag{Name: "force", Usage: "publish even if the current profile is not found"},
+				},
+				Usage:     "update profile",
+				UsageText: "algia profile-set --name [name] --about [about]",
+				HelpName:  "profile-set",
+				Action:    cmd.DoProfileSet,
+			},
+			{
+				Name: "following",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "json", Usage: "output JSON"},
+				},
+				Usage:     "show following users",
+				UsageText: "algia following",
+				HelpName:  "following",
+				Action:    cmd.DoFollowing,
+			},
+			{
+				Name: "follow",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "petname", Usage: "petname"},
+					&cli.BoolFlag{Name: "force", Usage: "publish even if the contact list looks truncated"},
+				},
+				Usage:     "follow users",
+				UsageText: "algia follow [npub|nprofile|nip05]",
+				HelpName:  "follow",
+				ArgsUsage: "[npub|nprofile|nip05]",
+				Action:    cmd.DoFollow,
+			},
+			{
+				Name: "unfollow",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "force", Usage: "publish even if the contact list looks truncated"},
+				},
+				Usage:     "unfollow users",
+				UsageText: "algia unfollow [npub|nprofile|nip05]",
+				HelpName:  "unfollow",
+				ArgsUsage: "[npub|nprofile|nip05]",
+				Action:    cmd.DoUnfollow,
+			},
+			{
+				Name:  "key",
+				Usage: "manage private key",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "encrypt",
+						Usage:     "encrypt private key with passphrase (NIP-49)",
+						UsageText: "algia key encrypt",
+						HelpName:  "encrypt",
+						Action:    cmd.DoKeyEncrypt,
+					},
+					{
+						Name:      "decrypt",
+						Usage:     "decrypt private key",
+						UsageText: "algia key decrypt",
+						HelpName:  "decrypt",
+						Action:    cmd.DoKeyDecrypt,
+					},
+				},
+			},
+			{
+				Name: "relays",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "json", Usage: "output JSON"},
+				},
+				Usage:     "show relays",
+				UsageText: "algia relays",
+				HelpName:  "relays",
+				Action:    cmd.DoRelays,
+				Subcommands: []*cli.Command{
+					{
+						Name: "add",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{Name: "read", Value: true, Usage: "read from the relay"},
+							&cli.BoolFlag{Name: "write", Value: true, Usage: "write to the relay"},
+							&cli.BoolFlag{Name: "search", Usage: "search on the relay"},
+							&cli.BoolFlag{Name: "publish", Usage: "publish relay list"},
+						},
+						Usage:     "add relays",
+						UsageText: "algia relays add [url...]",
+						HelpName:  "add",
+						ArgsUsage: "[url...]",
+						Action:    cmd.DoRelaysAdd,
+					},
+					{
+						Name: "remove",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{Name: "publish", Usage: "publish relay list"},
+						},
+						Usage:     "remove relays",
+						UsageText: "algia relays remove [url...]",
+						HelpName:  "remove",
+						ArgsUsage: "[url...]",
+						Action:    cmd.DoRelaysRemove,
+					},
+					{
+						Name:      "publish",
+						Usage:     "publish relay list (NIP-65)",
+						UsageText: "algia relays publish",
+						HelpName:  "publish",
+						Action:    cmd.DoRelaysPublish,
+					},
+				},
+			},
 			{
 				Name:      "powa",
 				Usage:     "post ぽわ〜",
@@ -252,6 +634,75 @@ func main() {
 				HelpName:  "zap",
 				Action:    cmd.DoZap,
 			},
+			{
+				Name: "encode",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "npub",
+						Flags:     []cli.Flag{&cli.BoolFlag{Name: "uri", Usage: "output nostr: URI"}},
+						Usage:     "encode public key",
+						UsageText: "algia encode npub [hex]",
+						Action:    cmd.DoEncodeNpub,
+					},
+					{
+						Name:      "nsec",
+						Flags:     []cli.Flag{&cli.BoolFlag{Name: "uri", Usage: "output nostr: URI"}},
+						Usage:     "encode private key",
+						UsageText: "algia encode nsec [hex]",
+						Action:    cmd.DoEncodeNsec,
+					},
+					{
+						Name:      "note",
+						Flags:     []cli.Flag{&cli.BoolFlag{Name: "uri", Usage: "output nostr: URI"}},
+						Usage:     "encode event id",
+						UsageText: "algia encode note [hex]",
+						Action:    cmd.DoEncodeNote,
+					},
+					{
+						Name: "nprofile",
+						Flags: []cli.Flag{
+							&cli.StringSliceFlag{Name: "relay", Usage: "relay hints"},
+							&cli.BoolFlag{Name: "uri", Usage: "output nostr: URI"},
+						},
+						Usage:     "encode profile",
+						UsageText: "algia encode nprofile --relay [relay] [hex]",
+						Action:    cmd.DoEncodeNprofile,
+					},
+					{
+						Name: "nevent",
+						Flags: []cli.Flag{
+							&cli.StringSliceFlag{Name: "relay", Usage: "relay hints"},
+							&cli.StringFlag{Name: "author", Usage: "author"},
+							&cli.IntFlag{Name: "kind", Usage: "kind"},
+							&cli.BoolFlag{Name: "uri", Usage: "output nostr: URI"},
+						},
+						Usage:     "encode event",
+						UsageText: "algia encode nevent --relay [relay] --author [pubkey] --kind [kind] [hex]",
+						Action:    cmd.DoEncodeNevent,
+					},
+					{
+						Name: "naddr",
+						Flags: []cli.Flag{
+							&cli.StringSliceFlag{Name: "relay", Usage: "relay hints"},
+							&cli.BoolFlag{Name: "uri", Usage: "output nostr: URI"},
+						},
+						Usage:     "encode addressable event",
+						UsageText: "algia encode naddr --relay [relay] [kind:pubkey:identifier]",
+						Action:    cmd.DoEncodeNaddr,
+					},
+				},
+				Usage:     "encode NIP-19 entities",
+				UsageText: "algia encode [npub|nsec|note|nprofile|nevent|naddr] [input...]",
+				HelpName:  "encode",
+			},
+			{
+				Name:      "decode",
+				Usage:     "decode NIP-19 entities",
+				UsageText: "algia decode [code...]",
+				HelpName:  "decode",
+				ArgsUsage: "[code...]",
+				Action:    cmd.DoDecode,
+			},
 			{
 				Name:      "version",
 				Usage:     "show version",
@@ -261,7 +712,8 @@ func main() {
 			},
 		},
 		Before: func(cCtx *cli.Context) error {
-			if cCtx.Args().Get(0) == "version" {
+			switch cCtx.Args().Get(0) {
+			case "version", "init", "encode", "decode":
 				return nil
 			}
 			profile := cCtx.String("a")
@@ -273,6 +725,13 @@ func main() {
 				"config": cfg,
 			}
 			cfg.Verbose = cCtx.Bool("V")
+			if cCtx.Bool("outbox") {
+				cfg.Outbox = true
+			}
+			cfg.Offline = cCtx.Bool("offline")
+			cfg.SinceCache = cCtx.Bool("since-cache")
+			cfg.RawContent = cCtx.Bool("raw-content")
+			cfg.PassphraseFD = cCtx.Int("passphrase-fd")
 			relays := cCtx.String("relays")
 			if strings.TrimSpace(relays) != "" {
 				cfg.Relays = make(map[string]domain.Relay)
@@ -286,6 +745,12 @@ func main() {
 			}
 			return nil
 		},
+		After: func(cCtx *cli.Context) error {
+			if cfg, ok := cCtx.App.Metadata["config"].(*domain.Config); ok {
+				cfg.Close()
+			}
+			return nil
+		},
 	}
 
 	if err := app.Run(os.Args); err != nil {